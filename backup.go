@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// BackupProvider stores backups of the alias file. Content is passed through
+// unchanged; encryption happens before Save and after Load.
+type BackupProvider interface {
+	// Name is shown in messages, e.g. "GitHub Gist"
+	Name() string
+	// Save stores content as the latest backup
+	Save(ctx context.Context, content []byte) error
+	// Load returns the latest backup, or errNoBackup when there is none
+	Load(ctx context.Context) ([]byte, error)
+	// Revisions returns up to limit earlier backups, newest first
+	Revisions(ctx context.Context, limit int) ([]BackupRevision, error)
+}
+
+// errNoBackup is returned by BackupProvider.Load before the first backup
+var errNoBackup = errors.New("no backup found")
+
+// manifestProvider is implemented by providers that store the alias manifest
+// (see aliasManifest) next to the alias file
+type manifestProvider interface {
+	// SaveWithManifest stores content and manifest as the latest backup
+	SaveWithManifest(ctx context.Context, content, manifest []byte) error
+	// LoadManifest returns the manifest of the latest backup, or errNoBackup
+	// when it has none
+	LoadManifest(ctx context.Context) ([]byte, error)
+}
+
+// backupTarget registers a backup provider. open creates the provider,
+// asking for credentials when needed, and passes it to fn. settings builds
+// the provider's part of the Backup settings page and may be nil.
+type backupTarget struct {
+	ID       string
+	Label    string
+	open     func(am *AliasManager, fn func(BackupProvider))
+	settings func(am *AliasManager) fyne.CanvasObject
+}
+
+// backupTargets lists the available providers; the first is the default
+var backupTargets = []backupTarget{
+	{ID: "gist", Label: "GitHub Gist", open: openGistProvider, settings: gistSettings},
+	{ID: "gitlab", Label: "GitLab snippet", open: openGitLabProvider, settings: gitlabSettings},
+	{ID: "gitea", Label: "Gitea repository", open: openGiteaProvider, settings: giteaSettings},
+	{ID: "git", Label: "Git repository", open: openGitProvider, settings: gitSettings},
+	{ID: "webdav", Label: "WebDAV folder", open: openWebDAVProvider, settings: webdavSettings},
+	{ID: "s3", Label: "S3-compatible bucket", open: openS3Provider, settings: s3Settings},
+}
+
+// backupTargetByID returns the registered target, falling back to the default
+func backupTargetByID(id string) backupTarget {
+	for _, t := range backupTargets {
+		if t.ID == id {
+			return t
+		}
+	}
+	return backupTargets[0]
+}
+
+// withBackupProvider opens the configured backup provider and calls fn with it
+func (am *AliasManager) withBackupProvider(fn func(BackupProvider)) {
+	backupTargetByID(am.config.BackupProvider).open(am, fn)
+}
+
+// backupAliases uploads the alias file to the configured provider
+func (am *AliasManager) backupAliases() {
+	am.withBackupProvider(func(p BackupProvider) {
+		am.backupContent(func(content []byte) {
+			am.uploadBackup(p, content)
+		})
+	})
+}
+
+// backupContent reads the content to back up and passes it to fn. Under
+// sandboxing the user is asked to pick the alias file instead.
+func (am *AliasManager) backupContent(fn func([]byte)) {
+	if am.config.targetShell() == shellFish {
+		// fish aliases live in many files; back up their alias-file rendering
+		fn([]byte(renderAliases(am.aliases)))
+		return
+	}
+	home, _ := homeDir()
+	content, err := os.ReadFile(am.aliasFile(home))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// proceed with empty content
+			fn([]byte(""))
+			return
+		}
+		if os.IsPermission(err) {
+			// Cannot read dotfile due to confinement: ask user to select file to backup
+			fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, rerr error) {
+				if rerr != nil || reader == nil {
+					return
+				}
+				defer reader.Close()
+				b, _ := io.ReadAll(reader)
+				fn(b)
+			}, am.window)
+			fd.SetFilter(storage.NewExtensionFileFilter([]string{"aliases", "txt", "sh"}))
+			fd.Show()
+			return
+		}
+		dialog.ShowError(err, am.window)
+		return
+	}
+	fn(content)
+}
+
+// uploadBackup saves content with the provider, together with the alias
+// manifest when the provider stores one. Both are encrypted when enabled.
+func (am *AliasManager) uploadBackup(p BackupProvider, content []byte) {
+	var manifest []byte
+	if _, ok := p.(manifestProvider); ok {
+		var err error
+		if manifest, err = am.buildManifest(content); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to build the alias manifest: %v", err), am.window)
+			return
+		}
+	}
+	target := backupTargetByID(am.config.BackupProvider).ID
+	var gist string
+	if gp, ok := p.(*gistProvider); ok {
+		gist = gp.target
+	}
+	am.encryptIfEnabled(content, func(content []byte) {
+		am.encryptIfEnabled(manifest, func(manifest []byte) {
+			am.runInBackground("Backup", "Backing up to "+p.Name()+"…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+				return saveBackup(ctx, p, content, manifest)
+			}, func(err error) {
+				if err != nil && isTemporary(err) {
+					am.queueBackup(&pendingBackup{provider: p, target: target, gist: gist, content: content, manifest: manifest, err: err})
+					dialog.ShowInformation("Backup", fmt.Sprintf("The backup could not be sent: %v\n\nIt is kept and sent to %s automatically once that works again, also after you quit and start the app again.", err, p.Name()), am.window)
+					return
+				}
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("Failed to back up to %s: %v", p.Name(), err), am.window)
+					return
+				}
+				am.dropPendingBackup(p)
+				dialog.ShowInformation("Backup", fmt.Sprintf("Aliases backed up to %s successfully!", p.Name()), am.window)
+			})
+		})
+	})
+}
+
+// encryptIfEnabled passes content to fn, encrypted when backups are
+// encrypted. Empty content is passed unchanged.
+func (am *AliasManager) encryptIfEnabled(content []byte, fn func([]byte)) {
+	if !am.config.EncryptBackups || content == nil || isEncryptedBackup(content) {
+		fn(content)
+		return
+	}
+	am.sealBackup(content, fn)
+}
+
+// loadBackup returns the latest backup of p and its manifest, which is nil
+// when p stores none or the backup was made without one
+func loadBackup(ctx context.Context, p BackupProvider) (content, manifest []byte, err error) {
+	if content, err = p.Load(ctx); err != nil {
+		return nil, nil, err
+	}
+	if mp, ok := p.(manifestProvider); ok {
+		manifest, err = mp.LoadManifest(ctx)
+		if errors.Is(err, errNoBackup) {
+			return content, nil, nil
+		}
+	}
+	return content, manifest, err
+}
+
+// restoreAliases downloads the latest backup and previews the changes. A
+// manifest is restored from when the provider has one; backups made before
+// manifests existed only have the alias file.
+func (am *AliasManager) restoreAliases() {
+	am.withBackupProvider(func(p BackupProvider) {
+		var content, manifest []byte
+		am.runInBackground("Restore", "Downloading the backup from "+p.Name()+"…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+			var err error
+			content, manifest, err = loadBackup(ctx, p)
+			return err
+		}, func(err error) {
+			if errors.Is(err, errNoBackup) {
+				dialog.ShowInformation("Restore", "No backup found. Please backup first.", am.window)
+				return
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to restore from %s: %v", p.Name(), err), am.window)
+				return
+			}
+			am.restoreBackup("Restore", content, manifest)
+		})
+	})
+}
+
+// backupSettings builds the Backup settings page: the provider selection,
+// the selected provider's own settings and encryption
+func (am *AliasManager) backupSettings() fyne.CanvasObject {
+	providerBox := container.NewVBox()
+	showProvider := func(id string) {
+		providerBox.Objects = nil
+		if t := backupTargetByID(id); t.settings != nil {
+			providerBox.Add(t.settings(am))
+		}
+		providerBox.Refresh()
+	}
+
+	var labels []string
+	for _, t := range backupTargets {
+		labels = append(labels, t.Label)
+	}
+	targetSelect := widget.NewSelect(labels, nil)
+	targetSelect.SetSelected(backupTargetByID(am.config.BackupProvider).Label)
+	targetSelect.OnChanged = func(label string) {
+		for _, t := range backupTargets {
+			if t.Label == label && t.ID != am.config.BackupProvider {
+				am.config.BackupProvider = t.ID
+				if err := am.saveConfig(); err != nil {
+					dialog.ShowError(err, am.window)
+				}
+				showProvider(t.ID)
+			}
+		}
+	}
+	showProvider(am.config.BackupProvider)
+
+	encryptCheck := widget.NewCheck("Encrypt backups with a passphrase", nil)
+	encryptCheck.SetChecked(am.config.EncryptBackups)
+	encryptCheck.OnChanged = am.setEncryptBackups
+
+	form := widget.NewForm(
+		widget.NewFormItem("Back up to", targetSelect),
+		widget.NewFormItem("Encryption", encryptCheck),
+	)
+	return container.NewVScroll(container.NewVBox(form, providerBox))
+}
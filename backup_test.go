@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// memoryProvider is a BackupProvider keeping its backups in memory
+type memoryProvider struct {
+	// revisions holds the backups, oldest first
+	revisions []BackupRevision
+}
+
+func (p *memoryProvider) Name() string { return "memory" }
+
+func (p *memoryProvider) Save(ctx context.Context, content []byte) error {
+	return p.save(content, nil)
+}
+
+func (p *memoryProvider) save(content, manifest []byte) error {
+	p.revisions = append(p.revisions, BackupRevision{
+		Version:  fmt.Sprint(len(p.revisions) + 1),
+		Time:     time.Now().Format(time.RFC3339),
+		Content:  append([]byte{}, content...),
+		Manifest: append([]byte(nil), manifest...),
+		Aliases:  aliasCount(content),
+	})
+	return nil
+}
+
+func (p *memoryProvider) Load(ctx context.Context) ([]byte, error) {
+	if len(p.revisions) == 0 {
+		return nil, errNoBackup
+	}
+	return p.revisions[len(p.revisions)-1].Content, nil
+}
+
+func (p *memoryProvider) Revisions(ctx context.Context, limit int) ([]BackupRevision, error) {
+	var revs []BackupRevision
+	for i := len(p.revisions) - 1; i >= 0 && len(revs) < limit; i-- {
+		revs = append(revs, p.revisions[i])
+	}
+	return revs, nil
+}
+
+// memoryManifestProvider is a memoryProvider that stores manifests as well
+type memoryManifestProvider struct {
+	memoryProvider
+}
+
+func (p *memoryManifestProvider) SaveWithManifest(ctx context.Context, content, manifest []byte) error {
+	return p.save(content, manifest)
+}
+
+func (p *memoryManifestProvider) LoadManifest(ctx context.Context) ([]byte, error) {
+	if len(p.revisions) == 0 || p.revisions[len(p.revisions)-1].Manifest == nil {
+		return nil, errNoBackup
+	}
+	return p.revisions[len(p.revisions)-1].Manifest, nil
+}
+
+// testManager returns a manager with aliases from the main file and an extra
+// file in a temporary home directory
+func testManager(t *testing.T) (*AliasManager, string) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	extra := filepath.Join(home, ".work_aliases")
+	am := &AliasManager{
+		config: Config{AliasFiles: []string{"~/.work_aliases"}},
+		aliases: []Alias{
+			{Name: "ll", Command: "ls -l", Source: filepath.Join(home, ".bash_aliases")},
+			{Name: "deploy", Command: "make deploy", Source: extra},
+			{Name: "G", Command: "| grep", Type: "global"},
+		},
+		usage: map[string]AliasUsage{"ll": {Count: 7}},
+	}
+	return am, home
+}
+
+func TestBackupWithManifest(t *testing.T) {
+	am, home := testManager(t)
+	ctx := context.Background()
+	p := &memoryManifestProvider{}
+	content := []byte(renderAliases(am.aliases))
+	manifest, err := am.buildManifest(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveBackup(ctx, p, content, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	gotContent, gotManifest, err := loadBackup(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gotContent) != string(content) {
+		t.Errorf("content = %q, want %q", gotContent, content)
+	}
+	m, err := parseManifest(gotManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.matches(gotContent); err != nil {
+		t.Error(err)
+	}
+	if err := m.matches([]byte("alias other='x'\n")); err == nil {
+		t.Error("manifest matched a different alias file")
+	}
+	sources := aliasSources(shellBash, home, am.aliasFile(home), am.config.AliasFiles)
+	restored := m.aliases(home, sources)
+	want := []Alias{
+		{Name: "ll", Command: "ls -l"},
+		{Name: "deploy", Command: "make deploy", Source: filepath.Join(home, ".work_aliases")},
+		{Name: "G", Command: "| grep", Type: "global"},
+	}
+	if !reflect.DeepEqual(restored, want) {
+		t.Errorf("restored %+v, want %+v", restored, want)
+	}
+	if u := m.usage()["ll"]; u.Count != 7 {
+		t.Errorf("usage of ll = %d, want 7", u.Count)
+	}
+}
+
+func TestBackupWithoutManifest(t *testing.T) {
+	am, _ := testManager(t)
+	ctx := context.Background()
+	p := &memoryProvider{}
+	content := []byte(renderAliases(am.aliases))
+	manifest, err := am.buildManifest(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveBackup(ctx, p, content, manifest); err != nil {
+		t.Fatal(err)
+	}
+	if p.revisions[0].Manifest != nil {
+		t.Error("a provider without manifest support stored one")
+	}
+
+	gotContent, gotManifest, err := loadBackup(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotManifest != nil {
+		t.Errorf("manifest = %q, want none", gotManifest)
+	}
+	restored, err := parseAliasContent(gotContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != len(am.aliases) {
+		t.Errorf("restored %d aliases, want %d", len(restored), len(am.aliases))
+	}
+}
+
+func TestLoadBackupWithoutManifestFromManifestProvider(t *testing.T) {
+	ctx := context.Background()
+	p := &memoryManifestProvider{}
+	// made by a version that did not write manifests
+	if err := p.Save(ctx, []byte("alias ll='ls -l'\n")); err != nil {
+		t.Fatal(err)
+	}
+	content, manifest, err := loadBackup(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest != nil || string(content) != "alias ll='ls -l'\n" {
+		t.Errorf("got content %q and manifest %q", content, manifest)
+	}
+}
+
+func TestLoadBackupNoBackup(t *testing.T) {
+	ctx := context.Background()
+	for _, p := range []BackupProvider{&memoryProvider{}, &memoryManifestProvider{}} {
+		if _, _, err := loadBackup(ctx, p); !errors.Is(err, errNoBackup) {
+			t.Errorf("%T: err = %v, want errNoBackup", p, err)
+		}
+	}
+}
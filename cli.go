@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const cliUsage = `Usage: bash-alias-manager [--file PATH] [--config PATH] [--verbose] [command]
+
+Without a command the graphical alias manager is started.
+
+Options:
+  --file PATH    manage PATH instead of the configured alias file, for this
+                 run only (bash and zsh)
+  --config PATH  read and write the config file at PATH
+  --verbose      print which alias files and aliases are loaded to stderr
+
+Environment:
+  BAM_HOME       directory to use in place of the home directory for the
+                 alias, startup and config files
+
+Commands:
+  init bash|zsh  print shell code that loads the managed aliases, for use as
+                 eval "$(bash-alias-manager init bash)" in ~/.bashrc or
+                 eval "$(bash-alias-manager init zsh)" in ~/.zshrc
+  integration status [--shell bash|zsh|fish]
+                 show the shell integration state of the startup files
+                 (~/.bashrc, ~/.bash_profile, ~/.bash_login, ~/.profile or ~/.zshrc)
+  integration install|repair|remove [--dry-run] [--shell bash|zsh|fish] [file...]
+                 add, fix or delete the managed block, printing a diff of the
+                 changes; --dry-run only prints the diff
+  export fish|zsh|nushell|powershell
+                 print the aliases translated for another shell; aliases that
+                 cannot be translated automatically are listed on stderr
+  version        print the version
+  help           show this help
+`
+
+// verbose is set by the --verbose flag
+var verbose bool
+
+// debugf prints a progress message to stderr when --verbose is given
+func debugf(format string, args ...interface{}) {
+	if verbose {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// parseGlobalFlags applies the --file, --config and --verbose options, which
+// come before the command, and returns the remaining arguments
+func parseGlobalFlags(args []string) ([]string, error) {
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		if args[0] == "--verbose" {
+			verbose = true
+			args = args[1:]
+			continue
+		}
+		name, value, hasValue := strings.Cut(args[0], "=")
+		if name != "--file" && name != "--config" {
+			break
+		}
+		args = args[1:]
+		if !hasValue {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s needs a path", name)
+			}
+			value, args = args[0], args[1:]
+		}
+		if value == "" {
+			return nil, fmt.Errorf("%s needs a path", name)
+		}
+		// relative paths on the command line are relative to the working directory
+		if !strings.HasPrefix(value, "~") {
+			abs, err := filepath.Abs(value)
+			if err != nil {
+				return nil, err
+			}
+			value = abs
+		}
+		if name == "--file" {
+			aliasFileOverride = value
+		} else {
+			configFileOverride = value
+		}
+	}
+	return args, nil
+}
+
+// runCLI handles command line invocations and returns the process exit code
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	home, err := homeDir()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "init":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "Usage: bash-alias-manager init bash|zsh")
+			return 2
+		}
+		script, err := initScript(args[1], home)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		fmt.Fprint(stdout, script)
+		return 0
+	case "integration":
+		am := &AliasManager{}
+		if err := am.loadConfig(); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return runIntegrationCLI(args[1:], am.config, home, stdout, stderr)
+	case "export":
+		return runExportCLI(args[1:], stdout, stderr)
+	case "version", "--version", "-v":
+		fmt.Fprintln(stdout, Version)
+		return 0
+	case "help", "--help", "-h":
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "Unknown command %q\n\n%s", args[0], cliUsage)
+		return 2
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// resetGlobalFlags clears the options set by parseGlobalFlags when the test ends
+func resetGlobalFlags(t *testing.T) {
+	t.Cleanup(func() {
+		aliasFileOverride, configFileOverride, verbose = "", "", false
+	})
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	resetGlobalFlags(t)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		args         []string
+		rest         []string
+		file, config string
+		verbose      bool
+		err          bool
+	}{
+		{args: []string{"export", "fish"}, rest: []string{"export", "fish"}},
+		{args: []string{"--file", "aliases.sh", "export", "fish"}, rest: []string{"export", "fish"}, file: filepath.Join(wd, "aliases.sh")},
+		{args: []string{"--file=~/dotfiles/aliases.sh"}, rest: []string{}, file: "~/dotfiles/aliases.sh"},
+		{args: []string{"--config", "/etc/bam.json", "--file=/a", "init", "bash"}, rest: []string{"init", "bash"}, file: "/a", config: "/etc/bam.json"},
+		{args: []string{"--verbose", "--config=c.json"}, rest: []string{}, config: filepath.Join(wd, "c.json"), verbose: true},
+		// options after the command belong to the command
+		{args: []string{"integration", "status", "--file", "x"}, rest: []string{"integration", "status", "--file", "x"}},
+		{args: []string{"--shell", "zsh"}, rest: []string{"--shell", "zsh"}},
+		{args: []string{"--file"}, err: true},
+		{args: []string{"--config="}, err: true},
+	} {
+		aliasFileOverride, configFileOverride, verbose = "", "", false
+		rest, err := parseGlobalFlags(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: err = %v", tt.args, err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if len(rest) == 0 {
+			rest = []string{}
+		}
+		if !reflect.DeepEqual(rest, tt.rest) || aliasFileOverride != tt.file || configFileOverride != tt.config || verbose != tt.verbose {
+			t.Errorf("%q: rest %q, file %q, config %q, verbose %v", tt.args, rest, aliasFileOverride, configFileOverride, verbose)
+		}
+	}
+}
+
+func TestBAMHome(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a relative BAM_HOME is relative to the working directory
+	rel, err := filepath.Rel(wd, filepath.Join(dir, "scratch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BAM_HOME", rel)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	home, err := homeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "scratch"); home != want {
+		t.Fatalf("homeDir() = %q, want %q", home, want)
+	}
+	if got, want := configPath(home), filepath.Join(home, ".config", appDirName, "config.json"); got != want {
+		t.Errorf("configPath = %q, want %q", got, want)
+	}
+	am := &AliasManager{}
+	if got := am.aliasFile(home); got != filepath.Join(home, ".bash_aliases") {
+		t.Errorf("aliasFile = %q", got)
+	}
+}
+
+func TestAliasFileOverride(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	resetGlobalFlags(t)
+	for _, tt := range []struct {
+		config   Config
+		override string
+		want     string
+	}{
+		{Config{}, "", filepath.Join(home, ".bash_aliases")},
+		{Config{Shell: shellZsh}, "", filepath.Join(home, ".zsh_aliases")},
+		{Config{AliasFile: "~/dotfiles/aliases.sh"}, "", filepath.Join(home, "dotfiles", "aliases.sh")},
+		{Config{AliasFile: "dotfiles/aliases.sh"}, "", filepath.Join(home, "dotfiles", "aliases.sh")},
+		{Config{AliasFile: "/etc/aliases.sh"}, "", "/etc/aliases.sh"},
+		// --file wins over the configured file for this run
+		{Config{AliasFile: "~/dotfiles/aliases.sh"}, "/tmp/other.sh", "/tmp/other.sh"},
+		{Config{}, "~/other.sh", filepath.Join(home, "other.sh")},
+	} {
+		aliasFileOverride = tt.override
+		am := &AliasManager{config: tt.config}
+		if got := am.aliasFile(home); got != tt.want {
+			t.Errorf("%+v with --file %q: %q, want %q", tt.config, tt.override, got, tt.want)
+		}
+		// the configured path itself is unchanged by --file
+		if tt.config.AliasFile == "" {
+			continue
+		}
+		if got := aliasFilePath(tt.config.targetShell(), home, tt.config); got != expandPath(tt.config.AliasFile, home) {
+			t.Errorf("aliasFilePath = %q", got)
+		}
+	}
+}
+
+func TestConfigOverride(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	resetGlobalFlags(t)
+	// a legacy config in the home directory must not be read or moved
+	legacy := filepath.Join(home, legacyConfigFile)
+	if err := os.WriteFile(legacy, []byte(`{"shell":"zsh"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "test-config.json")
+	if _, err := parseGlobalFlags([]string{"--config", path}); err != nil {
+		t.Fatal(err)
+	}
+
+	am := &AliasManager{}
+	if err := am.loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if am.config.targetShell() != shellBash {
+		t.Errorf("read the legacy config: %+v", am.config)
+	}
+	am.config.AliasFile = "~/dotfiles/aliases.sh"
+	if err := am.saveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Config
+	if err := json.Unmarshal(data, &saved); err != nil || saved.AliasFile != "~/dotfiles/aliases.sh" {
+		t.Errorf("saved %s, %v", data, err)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("legacy config moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config", appDirName, "config.json")); !os.IsNotExist(err) {
+		t.Errorf("default config written: %v", err)
+	}
+}
+
+func TestRunCLIWithFileOverride(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	resetGlobalFlags(t)
+	if err := os.WriteFile(filepath.Join(home, ".bash_aliases"), []byte("alias main='true'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(t.TempDir(), "aliases.sh")
+	if err := os.WriteFile(other, []byte("alias other='true'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args, err := parseGlobalFlags([]string{"--file", other, "export", "zsh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runCLI(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, "alias other='true'") || strings.Contains(out, "main") {
+		t.Errorf("exported %q", out)
+	}
+}
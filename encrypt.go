@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// encryptedBackupHeader starts every encrypted backup so restore can tell it
+// apart from a plain alias file. The rest is the sealWithPassphrase output.
+const encryptedBackupHeader = "# bash-alias-manager encrypted backup v1\n"
+
+// isEncryptedBackup reports whether content is an encrypted backup
+func isEncryptedBackup(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(encryptedBackupHeader))
+}
+
+// encryptBackup encrypts alias file content for upload
+func encryptBackup(content []byte, passphrase string) ([]byte, error) {
+	sealed, err := sealWithPassphrase(content, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(encryptedBackupHeader), sealed...), nil
+}
+
+// decryptBackup reverses encryptBackup
+func decryptBackup(content []byte, passphrase string) ([]byte, error) {
+	if !isEncryptedBackup(content) {
+		return nil, fmt.Errorf("backup is not encrypted")
+	}
+	return openWithPassphrase(content[len(encryptedBackupHeader):], passphrase)
+}
+
+// sealBackup encrypts content with the backup passphrase, asking for it when
+// it was not entered yet this session, and passes the result to fn
+func (am *AliasManager) sealBackup(content []byte, fn func([]byte)) {
+	seal := func(passphrase string) {
+		sealed, err := encryptBackup(content, passphrase)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to encrypt backup: %v", err), am.window)
+			return
+		}
+		am.backupPassphrase = passphrase
+		fn(sealed)
+	}
+	if am.backupPassphrase != "" {
+		seal(am.backupPassphrase)
+		return
+	}
+	am.askPassphrase("Encrypt backup", "Backups are encrypted before upload. Enter the backup passphrase; it is needed to restore and cannot be recovered.", true, seal)
+}
+
+// openBackup decrypts downloaded content when it is an encrypted backup and
+// passes the alias file content to fn. Plain backups are passed unchanged.
+func (am *AliasManager) openBackup(content []byte, fn func([]byte)) {
+	if !isEncryptedBackup(content) {
+		fn(content)
+		return
+	}
+	open := func(passphrase string) {
+		plain, err := decryptBackup(content, passphrase)
+		if err != nil {
+			am.backupPassphrase = ""
+			if errors.Is(err, errWrongPassphrase) {
+				dialog.ShowError(fmt.Errorf("Wrong passphrase: the backup could not be decrypted. Nothing was restored."), am.window)
+				return
+			}
+			dialog.ShowError(fmt.Errorf("Failed to decrypt backup: %v", err), am.window)
+			return
+		}
+		am.backupPassphrase = passphrase
+		fn(plain)
+	}
+	if am.backupPassphrase != "" {
+		open(am.backupPassphrase)
+		return
+	}
+	am.askPassphrase("Decrypt backup", "This backup is encrypted. Enter the backup passphrase.", false, open)
+}
+
+// setEncryptBackups turns backup encryption on or off. Earlier revisions of
+// a Gist or snippet keep their plain text, so when turning it on the user
+// may start new ones instead.
+func (am *AliasManager) setEncryptBackups(enabled bool) {
+	save := func() {
+		am.config.EncryptBackups = enabled
+		if err := am.saveConfig(); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+	}
+	var kind string
+	var ids []*string
+	switch backupTargetByID(am.config.BackupProvider).ID {
+	case "gist":
+		kind = "Gist"
+		for i := range am.config.GistTargets {
+			if am.config.GistTargets[i].ID != "" {
+				ids = append(ids, &am.config.GistTargets[i].ID)
+			}
+		}
+	case "gitlab":
+		kind = "snippet"
+		if am.config.GitLabSnippetID != "" {
+			ids = append(ids, &am.config.GitLabSnippetID)
+		}
+	}
+	if !enabled || len(ids) == 0 {
+		save()
+		return
+	}
+	existing, fresh, old := kind, "a new "+kind, "The old "+kind+" is"
+	if len(ids) > 1 {
+		existing, fresh, old = kind+"s", "new "+kind+"s", "The old "+kind+"s are"
+	}
+	dialog.ShowConfirm("Encrypt backups",
+		fmt.Sprintf("The revision history of the existing %s still contains your aliases in plain text.\n\nStart %s for encrypted backups? %s left in place for you to delete.", existing, fresh, old),
+		func(restart bool) {
+			if restart {
+				for _, id := range ids {
+					*id = ""
+				}
+			}
+			save()
+		}, am.window)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryptBackup(t *testing.T) {
+	content := []byte("alias ll='ls -l'\n")
+	sealed, err := encryptBackup(content, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncryptedBackup(sealed) || bytes.Contains(sealed, []byte("ls -l")) {
+		t.Fatalf("not encrypted: %q", sealed)
+	}
+	if plain, err := decryptBackup(sealed, "pw"); err != nil || !bytes.Equal(plain, content) {
+		t.Errorf("decryptBackup = %q, %v", plain, err)
+	}
+	if _, err := decryptBackup(sealed, "other"); !errors.Is(err, errWrongPassphrase) {
+		t.Errorf("wrong passphrase: %v", err)
+	}
+	if _, err := decryptBackup(content, "pw"); err == nil {
+		t.Error("plain backup decrypted")
+	}
+}
+
+func TestDecryptBackupRejectsOversizedParameters(t *testing.T) {
+	// a downloaded backup asking for 1 TiB of scrypt memory
+	crafted := encryptedBackupHeader + `{"kdf":"scrypt","n":1073741824,"r":1024,"p":1,"salt":"AAAAAAAAAAAAAAAAAAAAAA==","nonce":"AAAAAAAAAAAAAAAA","data":"AA=="}`
+	if _, err := decryptBackup([]byte(crafted), "pw"); err == nil || errors.Is(err, errWrongPassphrase) {
+		t.Errorf("err = %v, want the parameters refused", err)
+	}
+}
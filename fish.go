@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fish has no alias file. Normal aliases are stored as one function file
+// each in ~/.config/fish/functions, abbreviations (Alias.Type "abbr") in a
+// conf.d file. fish loads both automatically, so no startup integration is
+// needed.
+
+const (
+	shellFish = "fish"
+
+	// fishManagedMarker is the first line of every file the app writes
+	fishManagedMarker = "# Managed by Bash Alias Manager"
+	// fishAbbrFile holds the managed abbreviations, inside conf.d
+	fishAbbrFile = "bash-alias-manager.fish"
+)
+
+// fishConfigDir returns the fish configuration directory. Like xdgDir it
+// ignores XDG_CONFIG_HOME when BAM_HOME is set.
+func fishConfigDir(home string) string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && os.Getenv("BAM_HOME") == "" {
+		return filepath.Join(dir, "fish")
+	}
+	return filepath.Join(home, ".config", "fish")
+}
+
+// skippedAliasesError reports aliases a save left out; the other aliases
+// were written
+type skippedAliasesError struct {
+	Issues []TranslationIssue
+}
+
+func (e *skippedAliasesError) Error() string {
+	var b strings.Builder
+	b.WriteString("Some aliases were not saved:")
+	for _, is := range e.Issues {
+		fmt.Fprintf(&b, "\n%s: %s", is.Alias, is.Reason)
+	}
+	return b.String()
+}
+
+// fishQuote quotes s as a fish single-quoted string
+func fishQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(s) + "'"
+}
+
+// fishUnquote reverses fishQuote, also accepting double-quoted and bare words
+func fishUnquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		r := strings.NewReplacer(`\\`, `\`, `\'`, `'`)
+		return r.Replace(s[1 : len(s)-1])
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		r := strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\$`, `$`)
+		return r.Replace(s[1 : len(s)-1])
+	}
+	return s
+}
+
+// fishFunction renders an alias as a fish function file, in the same shape
+// fish's own `alias` command produces
+func fishFunction(alias Alias) string {
+	body := alias.Command
+	wraps := " --wraps " + fishQuote(alias.Command)
+	// calling the function from itself would recurse, and fish refuses a
+	// function that wraps itself
+	if first, _, _ := strings.Cut(body, " "); first == alias.Name {
+		body = "command " + body
+		wraps = ""
+	}
+	return fmt.Sprintf("%s\nfunction %s%s --description %s\n    %s $argv\nend\n",
+		fishManagedMarker, alias.Name, wraps, fishQuote("alias "+alias.Name+"="+alias.Command), body)
+}
+
+// parseFishFunction reads a function file back into an alias. Only functions
+// written by the app or by fish's `alias` command are recognised.
+func parseFishFunction(content string) (Alias, bool) {
+	var name, body string
+	isAlias := strings.HasPrefix(content, fishManagedMarker)
+	inBody := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "function "):
+			fields := strings.Fields(line)
+			if len(fields) > 1 {
+				name = fields[1]
+			}
+			if strings.Contains(line, "--description 'alias ") || strings.Contains(line, "--description \"alias ") {
+				isAlias = true
+			}
+			inBody = true
+		case line == "end":
+			inBody = false
+		case inBody && body == "":
+			body = line
+		case inBody:
+			// multi-line functions are not aliases
+			return Alias{}, false
+		}
+	}
+	if !isAlias || name == "" || body == "" {
+		return Alias{}, false
+	}
+	body = strings.TrimSuffix(body, " $argv")
+	if strings.HasPrefix(body, "command ") {
+		if first, _, _ := strings.Cut(strings.TrimPrefix(body, "command "), " "); first == name {
+			body = strings.TrimPrefix(body, "command ")
+		}
+	}
+	return Alias{Name: name, Command: body}, true
+}
+
+// parseFishAbbr parses an `abbr -a [--] name 'expansion'` line
+func parseFishAbbr(line string) (Alias, bool) {
+	fields := strings.Fields(strings.TrimSpace(line))
+	if len(fields) < 3 || fields[0] != "abbr" {
+		return Alias{}, false
+	}
+	i := 1
+	for i < len(fields) && strings.HasPrefix(fields[i], "-") {
+		if fields[i] == "--" {
+			i++
+			break
+		}
+		i++
+	}
+	if i+1 >= len(fields) {
+		return Alias{}, false
+	}
+	// the expansion is everything after the name, keeping its inner spacing
+	rest := strings.TrimSpace(line)
+	for _, f := range fields[:i+1] {
+		rest = strings.TrimSpace(rest[len(f):])
+	}
+	return Alias{Name: fields[i], Command: fishUnquote(rest), Type: "abbr"}, true
+}
+
+// loadFishAliases reads the alias functions and managed abbreviations
+func loadFishAliases(home string) ([]Alias, error) {
+	dir := fishConfigDir(home)
+	aliases := []Alias{}
+
+	files, err := filepath.Glob(filepath.Join(dir, "functions", "*.fish"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			if os.IsPermission(err) {
+				return nil, fmt.Errorf("permission-denied")
+			}
+			return nil, err
+		}
+		if alias, ok := parseFishFunction(string(data)); ok {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "conf.d", fishAbbrFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if alias, ok := parseFishAbbr(line); ok {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases, nil
+}
+
+// saveFishAliases writes one function file per alias and the abbreviation
+// file, and removes alias functions that are no longer in the list. A
+// function file the app did not write is never overwritten; such aliases are
+// reported in a *skippedAliasesError.
+func saveFishAliases(home string, aliases []Alias) error {
+	dir := fishConfigDir(home)
+	funcDir := filepath.Join(dir, "functions")
+	confDir := filepath.Join(dir, "conf.d")
+	// checked up front so that a bad name does not leave a half-saved list
+	for _, alias := range aliases {
+		if alias.Type != "abbr" && strings.ContainsAny(alias.Name, "/ ") {
+			return fmt.Errorf("Invalid fish function name %q", alias.Name)
+		}
+	}
+	if err := os.MkdirAll(funcDir, 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(confDir, 0755); err != nil {
+		return err
+	}
+
+	keep := map[string]bool{}
+	var skipped []TranslationIssue
+	var abbrs strings.Builder
+	abbrs.WriteString(fishManagedMarker + ". Changes are overwritten on save.\n")
+	for _, alias := range aliases {
+		if alias.Type == "abbr" {
+			fmt.Fprintf(&abbrs, "abbr -a -- %s %s\n", alias.Name, fishQuote(alias.Command))
+			continue
+		}
+		path := filepath.Join(funcDir, alias.Name+".fish")
+		keep[path] = true
+		if data, err := os.ReadFile(path); err == nil && !strings.HasPrefix(string(data), fishManagedMarker) {
+			// written by hand or by fish's `alias --save`
+			if existing, ok := parseFishFunction(string(data)); !ok || existing.Command != alias.Command {
+				skipped = append(skipped, TranslationIssue{Alias: alias.Name, Reason: "a fish function not written by Bash Alias Manager exists in " + path})
+			}
+			continue
+		}
+		if err := os.WriteFile(path, []byte(fishFunction(alias)), 0644); err != nil {
+			return err
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(funcDir, "*.fish"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if keep[f] {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		if _, ok := parseFishFunction(string(data)); ok {
+			if err := os.Remove(f); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(confDir, fishAbbrFile), []byte(abbrs.String()), 0644); err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		return &skippedAliasesError{Issues: skipped}
+	}
+	return nil
+}
+
+// parseFishHistory reads fish's history file, a YAML-like list of
+// "- cmd: ..." entries each followed by "  when: <unix seconds>"
+func parseFishHistory(r io.Reader) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "- cmd: "):
+			cmd := strings.NewReplacer(`\n`, "\n", `\\`, `\`).Replace(line[len("- cmd: "):])
+			entries = append(entries, HistoryEntry{Command: strings.TrimSpace(cmd)})
+		case strings.HasPrefix(line, "  when: ") && len(entries) > 0:
+			if secs, err := strconv.ParseInt(strings.TrimSpace(line[len("  when: "):]), 10, 64); err == nil {
+				entries[len(entries)-1].Time = time.Unix(secs, 0)
+			}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// fishHistoryPath returns the location of fish's history file, ignoring
+// XDG_DATA_HOME when BAM_HOME is set
+func fishHistoryPath(home string) string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" && os.Getenv("BAM_HOME") == "" {
+		return filepath.Join(dir, "fish", "fish_history")
+	}
+	return filepath.Join(home, ".local", "share", "fish", "fish_history")
+}
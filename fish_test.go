@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFishPathsHonorBAMHome(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(xdg, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(xdg, "data"))
+
+	t.Setenv("BAM_HOME", "")
+	if got, want := fishConfigDir("/h"), filepath.Join(xdg, "config", "fish"); got != want {
+		t.Errorf("fishConfigDir = %q, want %q", got, want)
+	}
+	if got, want := fishHistoryPath("/h"), filepath.Join(xdg, "data", "fish", "fish_history"); got != want {
+		t.Errorf("fishHistoryPath = %q, want %q", got, want)
+	}
+
+	t.Setenv("BAM_HOME", "/scratch")
+	if got := fishConfigDir("/scratch"); got != "/scratch/.config/fish" {
+		t.Errorf("fishConfigDir with BAM_HOME = %q", got)
+	}
+	if got := fishHistoryPath("/scratch"); got != "/scratch/.local/share/fish/fish_history" {
+		t.Errorf("fishHistoryPath with BAM_HOME = %q", got)
+	}
+}
+
+func TestSaveFishAliasesValidatesFirst(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("BAM_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", config)
+	aliases := []Alias{
+		{Name: "ll", Command: "ls -l"},
+		{Name: "bad name", Command: "true"},
+	}
+	if err := saveFishAliases(t.TempDir(), aliases); err == nil {
+		t.Fatal("expected an error for an invalid function name")
+	}
+	if _, err := os.Stat(filepath.Join(config, "fish")); !os.IsNotExist(err) {
+		t.Errorf("fish config dir was written before validation: %v", err)
+	}
+}
+
+func TestSaveFishAliasesKeepsForeignFunctions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	funcDir := filepath.Join(fishConfigDir(home), "functions")
+	if err := os.MkdirAll(funcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	handWritten := "function gs\n    git status $argv\n    echo done\nend\n"
+	// what `alias --save gp 'git push'` writes
+	fishAlias := "# Defined via `source`\nfunction gp --wraps='git push' --description 'alias gp=git push'\n  git push $argv\n        \nend\n"
+	files := map[string]string{"gs.fish": handWritten, "gp.fish": fishAlias, "old.fish": fishFunction(Alias{Name: "old", Command: "true"})}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(funcDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	aliases := []Alias{
+		{Name: "gs", Command: "git status -s"},
+		// unchanged, so there is nothing to report
+		{Name: "gp", Command: "git push"},
+		{Name: "ll", Command: "ls -l"},
+		{Name: "e", Command: "nvim", Type: "abbr"},
+	}
+	err := saveFishAliases(home, aliases)
+	var skipped *skippedAliasesError
+	if !errors.As(err, &skipped) || len(skipped.Issues) != 1 || skipped.Issues[0].Alias != "gs" {
+		t.Fatalf("err = %v, want gs reported as skipped", err)
+	}
+	for name, want := range map[string]string{"gs.fish": handWritten, "gp.fish": fishAlias, "ll.fish": fishFunction(aliases[2])} {
+		if data, _ := os.ReadFile(filepath.Join(funcDir, name)); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(funcDir, "old.fish")); !os.IsNotExist(err) {
+		t.Errorf("removed alias function kept: %v", err)
+	}
+	abbrs, _ := os.ReadFile(filepath.Join(fishConfigDir(home), "conf.d", fishAbbrFile))
+	if !strings.Contains(string(abbrs), "abbr -a -- e 'nvim'") {
+		t.Errorf("abbreviations not written: %q", abbrs)
+	}
+
+	loaded, err := loadFishAliases(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, a := range loaded {
+		names = append(names, a.Name)
+	}
+	if strings.Join(names, " ") != "gp ll e" {
+		t.Errorf("loaded %q", names)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// forgeClient calls the REST API of a self-hostable forge (GitLab, Gitea)
+type forgeClient struct {
+	// api is the API root, e.g. https://gitlab.example.com/api/v4
+	api string
+	// header and value authenticate every request
+	header string
+	value  string
+}
+
+// forgeError is an API response with a non-2xx status
+type forgeError struct {
+	Status  int
+	Message string
+}
+
+func (e *forgeError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("status %d", e.Status)
+	}
+	return fmt.Sprintf("status %d: %s", e.Status, e.Message)
+}
+
+// isStatus reports whether err is an API response with the given status
+func isStatus(err error, status int) bool {
+	var fe *forgeError
+	return errors.As(err, &fe) && fe.Status == status
+}
+
+// forgeURL normalises a configured server address, using fallback when
+// none is set
+func forgeURL(configured, fallback string) string {
+	u := strings.TrimRight(strings.TrimSpace(configured), "/")
+	if u == "" {
+		return fallback
+	}
+	if !strings.Contains(u, "://") {
+		u = "https://" + u
+	}
+	return u
+}
+
+// request sends a request to path below the API root and returns the body
+func (c *forgeClient) request(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.api+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(c.header, c.value)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// both forges answer with {"message": ...}
+		var msg struct {
+			Message interface{} `json:"message"`
+		}
+		fe := &forgeError{Status: resp.StatusCode}
+		if json.Unmarshal(data, &msg) == nil && msg.Message != nil {
+			fe.Message = fmt.Sprint(msg.Message)
+		}
+		return nil, fe
+	}
+	return data, nil
+}
+
+// call sends a JSON request and decodes the JSON response into out, which
+// may be nil
+func (c *forgeClient) call(ctx context.Context, method, path string, body, out interface{}) error {
+	data, err := c.request(ctx, method, path, body)
+	if err != nil || out == nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForgeURL(t *testing.T) {
+	for _, tt := range []struct{ configured, want string }{
+		{"", defaultGitLabURL},
+		{"  ", defaultGitLabURL},
+		{"gitlab.example.com", "https://gitlab.example.com"},
+		{"http://localhost:3000/", "http://localhost:3000"},
+		{" https://git.example.com/gitlab// ", "https://git.example.com/gitlab"},
+	} {
+		if got := forgeURL(tt.configured, defaultGitLabURL); got != tt.want {
+			t.Errorf("forgeURL(%q) = %q, want %q", tt.configured, got, tt.want)
+		}
+	}
+}
+
+func TestForgeClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(401)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/echo":
+			if r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(415)
+				return
+			}
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(body)
+		case "/api/missing":
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"message":"404 Not Found"}`)
+		case "/api/invalid":
+			// GitLab reports validation errors per field
+			w.WriteHeader(400)
+			fmt.Fprint(w, `{"message":{"title":["is missing"]}}`)
+		default:
+			w.WriteHeader(500)
+			fmt.Fprint(w, "<html>oops</html>")
+		}
+	}))
+	defer srv.Close()
+	c := &forgeClient{api: srv.URL + "/api", header: "X-Token", value: "secret"}
+	ctx := context.Background()
+
+	var out map[string]string
+	if err := c.call(ctx, "POST", "/echo", map[string]string{"a": "b"}, &out); err != nil || out["a"] != "b" {
+		t.Errorf("call = %v, %v", out, err)
+	}
+	for _, tt := range []struct {
+		path   string
+		status int
+		err    string
+	}{
+		{"/missing", 404, "status 404: 404 Not Found"},
+		{"/invalid", 400, "status 400: map[title:[is missing]]"},
+		{"/broken", 500, "status 500"},
+	} {
+		_, err := c.request(ctx, "PUT", tt.path, nil)
+		if !isStatus(err, tt.status) || err.Error() != tt.err {
+			t.Errorf("%s: %v, want %q", tt.path, err, tt.err)
+		}
+	}
+	c.value = "wrong"
+	if err := c.call(ctx, "GET", "/echo", nil, nil); !isStatus(err, 401) {
+		t.Errorf("wrong token: %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v53/github"
+	"golang.org/x/oauth2"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	// gistFileName is the file holding the aliases inside the backup Gist
+	gistFileName = "bash_aliases"
+
+	defaultGitHubAPIURL    = "https://api.github.com/"
+	defaultGitHubUploadURL = "https://uploads.github.com/"
+)
+
+// githubURLs returns the API and upload base URLs, ending in a slash. The
+// upload URL of GitHub Enterprise Server (/api/uploads/) is derived from its
+// API URL (/api/v3/) when not set; other servers get the API URL.
+func (c Config) githubURLs() (api, upload string) {
+	api, upload = strings.TrimSpace(c.GitHubAPIURL), strings.TrimSpace(c.GitHubUploadURL)
+	if api == "" {
+		api = defaultGitHubAPIURL
+		if upload == "" {
+			upload = defaultGitHubUploadURL
+		}
+	}
+	if !strings.HasSuffix(api, "/") {
+		api += "/"
+	}
+	if upload == "" {
+		upload = api
+		if strings.HasSuffix(api, "/api/v3/") {
+			upload = strings.TrimSuffix(api, "v3/") + "uploads/"
+		}
+	}
+	if !strings.HasSuffix(upload, "/") {
+		upload += "/"
+	}
+	return api, upload
+}
+
+// validateGitHubURL checks a base URL entered in Settings; empty is allowed
+func validateGitHubURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", raw)
+	}
+	return nil
+}
+
+// newGitHubClient returns a client authenticated with token for the
+// configured API. The URLs are used as entered, without adding /api/v3/.
+func newGitHubClient(token string, config Config) (*github.Client, error) {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	client := github.NewClient(oauth2.NewClient(ctx, ts))
+	api, upload := config.githubURLs()
+	var err error
+	if client.BaseURL, err = url.Parse(api); err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL: %v", err)
+	}
+	if client.UploadURL, err = url.Parse(upload); err != nil {
+		return nil, fmt.Errorf("invalid GitHub upload URL: %v", err)
+	}
+	return client, nil
+}
+
+// gistProvider backs up to a private GitHub Gist, creating it on the first
+// backup. Every backup is a new Gist revision.
+type gistProvider struct {
+	client *github.Client
+	// web is the GitHub web address, for guidance in errors
+	web string
+	id  string
+	// file is the alias file inside the Gist and target names the Gist
+	// target; label is set to the target when there are several
+	file   string
+	target string
+	label  string
+	// created is called with the ID of a newly created Gist, from the
+	// goroutine saving the backup
+	created func(id string)
+}
+
+// openGistProvider asks which Gist target to use when there are several and
+// opens it
+func openGistProvider(am *AliasManager, fn func(BackupProvider)) {
+	am.chooseGistTarget(func(i int, t GistTarget) {
+		openGistTarget(am, i, t, fn)
+	})
+}
+
+// openGistTarget opens Gist target i once the GitHub token is available
+func openGistTarget(am *AliasManager, i int, t GistTarget, fn func(BackupProvider)) {
+	am.withToken(func() {
+		client, err := newGitHubClient(am.config.GitHubToken, am.config)
+		if err != nil {
+			dialog.ShowError(err, am.window)
+			return
+		}
+		p := &gistProvider{client: client, web: am.config.githubWebURL(), id: t.ID, file: t.fileName(), target: t.Name, created: func(id string) {
+			am.onUI(func() {
+				am.config.setGistID(i, id)
+				if err := am.saveConfig(); err != nil {
+					dialog.ShowError(fmt.Errorf("Failed to record the new Gist %s: %v", id, err), am.window)
+				}
+			})
+		}}
+		if len(am.config.gistTargets()) > 1 {
+			p.label = t.Name
+		}
+		fn(p)
+	})
+}
+
+func (g *gistProvider) Name() string {
+	if g.label != "" {
+		return fmt.Sprintf("GitHub Gist %q", g.label)
+	}
+	return "GitHub Gist"
+}
+
+// validate checks the token and its scopes before it is used, for a
+// clearer error
+func (g *gistProvider) validate(ctx context.Context) error {
+	return checkGitHubScopes(ctx, g.client, g.web)
+}
+
+func (g *gistProvider) Save(ctx context.Context, content []byte) error {
+	return g.SaveWithManifest(ctx, content, nil)
+}
+
+// SaveWithManifest stores content and, when not nil, the manifest in one
+// Gist revision
+func (g *gistProvider) SaveWithManifest(ctx context.Context, content, manifest []byte) error {
+	if err := g.validate(ctx); err != nil {
+		return err
+	}
+	gist := &github.Gist{
+		Description: github.String("Bash Aliases Backup"),
+		Public:      github.Bool(false),
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(g.file): {Content: github.String(string(content))},
+		},
+	}
+	if manifest != nil {
+		gist.Files[github.GistFilename(manifestName(g.file))] = github.GistFile{Content: github.String(string(manifest))}
+	}
+
+	if g.id != "" {
+		if _, _, err := g.client.Gists.Edit(ctx, g.id, gist); err != nil {
+			return fmt.Errorf("updating the Gist failed: %w", err)
+		}
+		return nil
+	}
+	created, resp, err := g.client.Gists.Create(ctx, gist)
+	if err != nil {
+		// 403/404 usually mean the token lacks the 'gist' scope
+		if _, limited := rateLimitReset(err); resp != nil && (resp.StatusCode == 403 || resp.StatusCode == 404) && !limited {
+			return fmt.Errorf("creating the Gist failed (status %d). Ensure your GitHub token has the 'gist' scope and is valid. Error: %v", resp.StatusCode, err)
+		}
+		return fmt.Errorf("creating the Gist failed: %w", err)
+	}
+	g.id = created.GetID()
+	g.created(g.id)
+	return nil
+}
+
+func (g *gistProvider) Load(ctx context.Context) ([]byte, error) {
+	if g.id == "" {
+		return nil, errNoBackup
+	}
+	if err := g.validate(ctx); err != nil {
+		return nil, err
+	}
+	return g.loadFile(ctx, g.file)
+}
+
+func (g *gistProvider) LoadManifest(ctx context.Context) ([]byte, error) {
+	if g.id == "" {
+		return nil, errNoBackup
+	}
+	return g.loadFile(ctx, manifestName(g.file))
+}
+
+// loadFile returns a file of the Gist, or errNoBackup when it has none
+func (g *gistProvider) loadFile(ctx context.Context, name string) ([]byte, error) {
+	gist, _, err := g.client.Gists.Get(ctx, g.id)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := gist.Files[github.GistFilename(name)]
+	if !ok {
+		// a Gist shared with other targets before this one's first backup,
+		// or a backup made before manifests existed
+		return nil, errNoBackup
+	}
+	return []byte(file.GetContent()), nil
+}
+
+func (g *gistProvider) Revisions(ctx context.Context, limit int) ([]BackupRevision, error) {
+	if g.id == "" {
+		return nil, errNoBackup
+	}
+	commits, _, err := g.client.Gists.ListCommits(ctx, g.id, &github.ListOptions{PerPage: limit})
+	if err != nil {
+		return nil, err
+	}
+	var revisions []BackupRevision
+	for _, c := range commits {
+		gist, _, err := g.client.Gists.GetRevision(ctx, g.id, c.GetVersion())
+		if err != nil {
+			return nil, err
+		}
+		file, ok := gist.Files[github.GistFilename(g.file)]
+		if !ok {
+			continue
+		}
+		content := []byte(file.GetContent())
+		var manifest []byte
+		if m, ok := gist.Files[github.GistFilename(manifestName(g.file))]; ok {
+			manifest = []byte(m.GetContent())
+		}
+		if n := len(revisions); n > 0 && bytes.Equal(revisions[n-1].Content, content) {
+			// another file of a shared Gist changed; date the revision by
+			// its oldest commit
+			revisions[n-1].Version = c.GetVersion()
+			revisions[n-1].Time = c.GetCommittedAt().Local().Format("2006-01-02 15:04")
+			revisions[n-1].Manifest = manifest
+			continue
+		}
+		revisions = append(revisions, BackupRevision{
+			Version:  c.GetVersion(),
+			Time:     c.GetCommittedAt().Local().Format("2006-01-02 15:04"),
+			Content:  content,
+			Manifest: manifest,
+			Aliases:  aliasCount(content),
+		})
+	}
+	return revisions, nil
+}
+
+// gistSettings configures the Gist targets and GitHub Enterprise Server.
+// Changing the API URL forgets the stored token, which belongs to the old
+// server.
+func gistSettings(am *AliasManager) fyne.CanvasObject {
+	targetsEditor, editedTargets := am.gistTargetSettings()
+	apiEntry := widget.NewEntry()
+	apiEntry.SetPlaceHolder(defaultGitHubAPIURL + " or https://github.example.com/api/v3/")
+	apiEntry.SetText(am.config.GitHubAPIURL)
+	uploadEntry := widget.NewEntry()
+	uploadEntry.SetPlaceHolder("derived from the API URL")
+	uploadEntry.SetText(am.config.GitHubUploadURL)
+	clientEntry := widget.NewEntry()
+	clientEntry.SetPlaceHolder("built-in")
+	if OAuthClientID == "" {
+		clientEntry.SetPlaceHolder("none: paste a personal access token instead")
+	}
+	clientEntry.SetText(am.config.GitHubClientID)
+
+	applyBtn := widget.NewButton("Apply", func() {
+		api := strings.TrimSpace(apiEntry.Text)
+		upload := strings.TrimSpace(uploadEntry.Text)
+		for _, u := range []string{api, upload} {
+			if err := validateGitHubURL(u); err != nil {
+				dialog.ShowError(err, am.window)
+				return
+			}
+		}
+		targets := editedTargets()
+		if err := validateGistTargets(targets); err != nil {
+			dialog.ShowError(err, am.window)
+			return
+		}
+		before, _ := am.config.githubURLs()
+		am.config.GistTargets = targets
+		am.config.GitHubAPIURL = api
+		am.config.GitHubUploadURL = upload
+		am.config.GitHubClientID = strings.TrimSpace(clientEntry.Text)
+		if after, _ := am.config.githubURLs(); after != before {
+			if err := am.forgetToken(serviceGitHub); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to remove the GitHub token: %v", err), am.window)
+			}
+		}
+		if err := am.saveConfig(); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+	})
+	form := widget.NewForm(
+		widget.NewFormItem("GitHub account", am.githubAccount()),
+		widget.NewFormItem("GitHub API URL", apiEntry),
+		widget.NewFormItem("Upload URL", uploadEntry),
+		widget.NewFormItem("OAuth client ID", clientEntry),
+	)
+	note := widget.NewLabel("The API URL is also used to check for updates.")
+	targetsLabel := widget.NewLabel("Gist targets: several names let you keep separate backups, e.g. work and personal; targets may share a Gist using different files.")
+	targetsLabel.Wrapping = fyne.TextWrapWord
+	return container.NewVBox(form, note, targetsLabel, targetsEditor, container.NewHBox(applyBtn))
+}
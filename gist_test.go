@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubURLs(t *testing.T) {
+	for _, tt := range []struct {
+		api, upload         string
+		wantAPI, wantUpload string
+	}{
+		{"", "", "https://api.github.com/", "https://uploads.github.com/"},
+		{"  ", "", "https://api.github.com/", "https://uploads.github.com/"},
+		{"https://ghe.example.com/api/v3", "", "https://ghe.example.com/api/v3/", "https://ghe.example.com/api/uploads/"},
+		{"https://ghe.example.com/api/v3/", "https://up.example.com", "https://ghe.example.com/api/v3/", "https://up.example.com/"},
+		{"http://127.0.0.1:8080", "", "http://127.0.0.1:8080/", "http://127.0.0.1:8080/"},
+		{"", "https://up.example.com/", "https://api.github.com/", "https://up.example.com/"},
+	} {
+		api, upload := Config{GitHubAPIURL: tt.api, GitHubUploadURL: tt.upload}.githubURLs()
+		if api != tt.wantAPI || upload != tt.wantUpload {
+			t.Errorf("githubURLs(%q, %q) = %q, %q; want %q, %q", tt.api, tt.upload, api, upload, tt.wantAPI, tt.wantUpload)
+		}
+	}
+}
+
+func TestValidateGitHubURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"":                                true,
+		"https://ghe.example.com/api/v3/": true,
+		"http://127.0.0.1:8080":           true,
+		"ghe.example.com/api/v3":          false,
+		"ftp://ghe.example.com/":          false,
+		"https://":                        false,
+		"://bad":                          false,
+	} {
+		if err := validateGitHubURL(raw); (err == nil) != ok {
+			t.Errorf("validateGitHubURL(%q) = %v", raw, err)
+		}
+	}
+}
+
+func TestReleaseURL(t *testing.T) {
+	for _, tt := range []struct{ api, want string }{
+		{"", "https://api.github.com/repos/" + releaseRepo + "/releases/latest"},
+		{"https://github.example.com/api/v3", "https://github.example.com/api/v3/repos/" + releaseRepo + "/releases/latest"},
+	} {
+		if got := (Config{GitHubAPIURL: tt.api}).releaseURL(); got != tt.want {
+			t.Errorf("releaseURL() with API %q = %q, want %q", tt.api, got, tt.want)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/"+releaseRepo+"/releases/latest" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write([]byte(`{"tag_name":"v9.9.9"}`))
+	}))
+	defer srv.Close()
+	rel, err := fetchRelease(context.Background(), Config{GitHubAPIURL: srv.URL}.releaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel["tag_name"] != "v9.9.9" {
+		t.Errorf("tag_name = %v", rel["tag_name"])
+	}
+}
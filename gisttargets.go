@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v53/github"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultGistTarget names the target used before any were configured
+const defaultGistTarget = "default"
+
+// GistTarget is a named Gist that backups can go to, e.g. "work" or
+// "personal". Several targets may share one Gist as long as their files
+// differ.
+type GistTarget struct {
+	Name string `json:"name"`
+	// ID is empty until the first backup creates the Gist
+	ID string `json:"id,omitempty"`
+	// File is the alias file inside the Gist; empty means gistFileName
+	File string `json:"file,omitempty"`
+}
+
+// fileName returns the file the target's aliases are stored in
+func (t GistTarget) fileName() string {
+	if t.File == "" {
+		return gistFileName
+	}
+	return t.File
+}
+
+// migrateGistID turns the single Gist ID of older configs into a target
+func (c *Config) migrateGistID() {
+	if c.GistID != "" && len(c.GistTargets) == 0 {
+		c.GistTargets = []GistTarget{{Name: defaultGistTarget, ID: c.GistID}}
+	}
+	c.GistID = ""
+}
+
+// gistTargets returns the configured targets, or the default one
+func (c Config) gistTargets() []GistTarget {
+	if len(c.GistTargets) == 0 {
+		return []GistTarget{{Name: defaultGistTarget}}
+	}
+	return c.GistTargets
+}
+
+// setGistID records the Gist created for target i
+func (c *Config) setGistID(i int, id string) {
+	if len(c.GistTargets) == 0 {
+		c.GistTargets = c.gistTargets()
+	}
+	c.GistTargets[i].ID = id
+}
+
+// validateGistTargets checks that names are set and unique and that targets
+// sharing a Gist use different files, including their manifests
+func validateGistTargets(targets []GistTarget) error {
+	names := map[string]bool{}
+	files := map[string]string{}
+	for _, t := range targets {
+		if t.Name == "" {
+			return fmt.Errorf("Every Gist target needs a name")
+		}
+		if names[t.Name] {
+			return fmt.Errorf("There are two Gist targets named %q", t.Name)
+		}
+		names[t.Name] = true
+		if strings.Contains(t.File, "/") {
+			return fmt.Errorf("The file of %q must not contain a slash", t.Name)
+		}
+		if t.ID == "" {
+			continue
+		}
+		for _, file := range []string{t.fileName(), manifestName(t.fileName())} {
+			key := t.ID + "/" + file
+			if other, ok := files[key]; ok && other != t.Name {
+				return fmt.Errorf("%q and %q store the same file in the same Gist", other, t.Name)
+			}
+			files[key] = t.Name
+		}
+	}
+	return nil
+}
+
+// chooseGistTarget calls fn with the index of the target to use, asking
+// which one when there are several
+func (am *AliasManager) chooseGistTarget(fn func(i int, t GistTarget)) {
+	targets := am.config.gistTargets()
+	if len(targets) == 1 {
+		fn(0, targets[0])
+		return
+	}
+	var names []string
+	for _, t := range targets {
+		names = append(names, t.Name)
+	}
+	sel := widget.NewSelect(names, nil)
+	sel.SetSelectedIndex(0)
+	dialog.ShowForm("Choose Gist", "OK", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Gist target", sel),
+	}, func(ok bool) {
+		if ok && sel.SelectedIndex() >= 0 {
+			fn(sel.SelectedIndex(), targets[sel.SelectedIndex()])
+		}
+	}, am.window)
+}
+
+// gistFiles returns the sorted file names of a Gist
+func gistFiles(g *github.Gist) []string {
+	var files []string
+	for name := range g.Files {
+		files = append(files, string(name))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// pickExistingGist lists the user's Gists and calls fn with the one chosen
+func (am *AliasManager) pickExistingGist(fn func(*github.Gist)) {
+	am.withToken(func() {
+		client, err := newGitHubClient(am.config.GitHubToken, am.config)
+		if err != nil {
+			dialog.ShowError(err, am.window)
+			return
+		}
+		var gists []*github.Gist
+		am.runInBackground("Gists", "Listing your Gists…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+			opts := &github.GistListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+			for {
+				page, resp, err := client.Gists.List(ctx, "", opts)
+				if err != nil {
+					return err
+				}
+				gists = append(gists, page...)
+				if resp.NextPage == 0 {
+					return nil
+				}
+				opts.Page = resp.NextPage
+			}
+		}, func(err error) {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to list your Gists: %v", err), am.window)
+				return
+			}
+			if len(gists) == 0 {
+				dialog.ShowInformation("Gists", "Your account has no Gists yet.", am.window)
+				return
+			}
+			am.showGistPicker(gists, fn)
+		})
+	})
+}
+
+// showGistPicker lets the user choose one of gists and calls fn with it
+func (am *AliasManager) showGistPicker(gists []*github.Gist, fn func(*github.Gist)) {
+	var d dialog.Dialog
+	list := widget.NewList(
+		func() int {
+			return len(gists)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			g := gists[i]
+			desc := g.GetDescription()
+			if desc == "" {
+				desc = "(no description)"
+			}
+			visibility := "secret"
+			if g.GetPublic() {
+				visibility = "public"
+			}
+			o.(*widget.Label).SetText(fmt.Sprintf("%s  [%s]  %s  (%s)",
+				desc, strings.Join(gistFiles(g), ", "), g.GetUpdatedAt().Local().Format("2006-01-02"), visibility))
+		},
+	)
+	list.OnSelected = func(i widget.ListItemID) {
+		d.Hide()
+		fn(gists[i])
+	}
+	d = dialog.NewCustom("Attach to an existing Gist", "Cancel", list, am.window)
+	d.Resize(fyne.NewSize(650, 400))
+	d.Show()
+}
+
+// gistTargetSettings edits the Gist targets. It returns the editor and a
+// function returning the edited targets for Apply.
+func (am *AliasManager) gistTargetSettings() (fyne.CanvasObject, func() []GistTarget) {
+	targets := append([]GistTarget(nil), am.config.gistTargets()...)
+	rows := container.NewVBox()
+	var rebuild func()
+	rebuild = func() {
+		rows.Objects = nil
+		for i := range targets {
+			i := i
+			name := widget.NewEntry()
+			name.SetPlaceHolder("name, e.g. work")
+			name.SetText(targets[i].Name)
+			name.OnChanged = func(s string) { targets[i].Name = strings.TrimSpace(s) }
+			id := widget.NewEntry()
+			id.SetPlaceHolder("Gist ID: created on first backup")
+			id.SetText(targets[i].ID)
+			id.OnChanged = func(s string) { targets[i].ID = strings.TrimSpace(s) }
+			file := widget.NewEntry()
+			file.SetPlaceHolder("file: " + gistFileName)
+			file.SetText(targets[i].File)
+			file.OnChanged = func(s string) { targets[i].File = strings.TrimSpace(s) }
+
+			attachBtn := widget.NewButton("Attach…", func() {
+				am.pickExistingGist(func(g *github.Gist) {
+					targets[i].ID = g.GetID()
+					files := gistFiles(g)
+					if _, ok := g.Files[github.GistFilename(targets[i].fileName())]; !ok && len(files) == 1 {
+						targets[i].File = files[0]
+					}
+					rebuild()
+				})
+			})
+			removeBtn := widget.NewButton("Remove", func() {
+				targets = append(targets[:i], targets[i+1:]...)
+				rebuild()
+			})
+			if len(targets) == 1 {
+				removeBtn.Disable()
+			}
+			rows.Add(container.NewBorder(nil, nil, nil, container.NewHBox(attachBtn, removeBtn),
+				container.NewGridWithColumns(3, name, id, file)))
+		}
+		rows.Refresh()
+	}
+	rebuild()
+
+	addBtn := widget.NewButton("Add Gist target", func() {
+		targets = append(targets, GistTarget{})
+		rebuild()
+	})
+	return container.NewVBox(rows, container.NewHBox(addBtn)), func() []GistTarget {
+		return append([]GistTarget(nil), targets...)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+func TestMigrateGistID(t *testing.T) {
+	c := Config{GistID: "abc"}
+	c.migrateGistID()
+	if want := []GistTarget{{Name: defaultGistTarget, ID: "abc"}}; c.GistID != "" || !reflect.DeepEqual(c.GistTargets, want) {
+		t.Errorf("migrated to %+v", c)
+	}
+	// configured targets win over a leftover ID
+	c = Config{GistID: "old", GistTargets: []GistTarget{{Name: "work", ID: "new"}}}
+	c.migrateGistID()
+	if c.GistID != "" || len(c.GistTargets) != 1 || c.GistTargets[0].ID != "new" {
+		t.Errorf("migrated to %+v", c)
+	}
+}
+
+func TestSetGistID(t *testing.T) {
+	var c Config
+	c.setGistID(0, "abc")
+	if want := []GistTarget{{Name: defaultGistTarget, ID: "abc"}}; !reflect.DeepEqual(c.GistTargets, want) {
+		t.Errorf("targets %+v, want %+v", c.GistTargets, want)
+	}
+	c.GistTargets = append(c.GistTargets, GistTarget{Name: "work"})
+	c.setGistID(1, "def")
+	if c.GistTargets[0].ID != "abc" || c.GistTargets[1].ID != "def" {
+		t.Errorf("targets %+v", c.GistTargets)
+	}
+}
+
+func TestValidateGistTargets(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		targets []GistTarget
+		err     string
+	}{
+		{name: "one", targets: []GistTarget{{Name: "default"}}},
+		{name: "separate Gists", targets: []GistTarget{{Name: "work", ID: "a"}, {Name: "home", ID: "b"}}},
+		{name: "not created yet", targets: []GistTarget{{Name: "work"}, {Name: "home"}}},
+		{name: "shared Gist, other files", targets: []GistTarget{{Name: "work", ID: "a"}, {Name: "home", ID: "a", File: "home_aliases"}}},
+		{name: "empty name", targets: []GistTarget{{Name: "work"}, {Name: ""}}, err: "needs a name"},
+		{name: "duplicate name", targets: []GistTarget{{Name: "work", ID: "a"}, {Name: "work", ID: "b"}}, err: `two Gist targets named "work"`},
+		{name: "slash in file", targets: []GistTarget{{Name: "work", File: "a/b"}}, err: "slash"},
+		{name: "same file", targets: []GistTarget{{Name: "work", ID: "a"}, {Name: "home", ID: "a", File: gistFileName}}, err: `"work" and "home"`},
+		// the manifest of one target would overwrite the other's
+		{name: "file is the other's manifest", targets: []GistTarget{{Name: "work", ID: "a"}, {Name: "home", ID: "a", File: manifestName(gistFileName)}}, err: "same file"},
+	} {
+		err := validateGistTargets(tt.targets)
+		if (tt.err == "" && err != nil) || (tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err))) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+// targetRow returns the name entry and the remove button of row i of the
+// Gist target editor
+func targetRow(t *testing.T, editor fyne.CanvasObject, i int) (*widget.Entry, *widget.Button) {
+	t.Helper()
+	rows := editor.(*fyne.Container).Objects[0].(*fyne.Container)
+	if i >= len(rows.Objects) {
+		t.Fatalf("editor has %d rows, want row %d", len(rows.Objects), i)
+	}
+	row := rows.Objects[i].(*fyne.Container)
+	fields := row.Objects[0].(*fyne.Container)
+	buttons := row.Objects[1].(*fyne.Container)
+	return fields.Objects[0].(*widget.Entry), buttons.Objects[1].(*widget.Button)
+}
+
+func TestGistTargetSettings(t *testing.T) {
+	test.NewApp()
+	am := &AliasManager{window: test.NewWindow(nil)}
+	am.config.GistTargets = []GistTarget{{Name: defaultGistTarget, ID: "abc"}}
+	editor, edited := am.gistTargetSettings()
+	add := editor.(*fyne.Container).Objects[1].(*fyne.Container).Objects[0].(*widget.Button)
+
+	// the only target cannot be removed
+	if _, remove := targetRow(t, editor, 0); !remove.Disabled() {
+		t.Error("the only target can be removed")
+	}
+
+	// add two targets and name them
+	test.Tap(add)
+	test.Tap(add)
+	name, _ := targetRow(t, editor, 1)
+	test.Type(name, " work ")
+	name, _ = targetRow(t, editor, 2)
+	test.Type(name, "home")
+	want := []GistTarget{{Name: defaultGistTarget, ID: "abc"}, {Name: "work"}, {Name: "home"}}
+	if got := edited(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after adding: %+v, want %+v", got, want)
+	}
+
+	// rename the first one
+	name, _ = targetRow(t, editor, 0)
+	name.SetText("personal")
+	want[0].Name = "personal"
+	if got := edited(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after renaming: %+v, want %+v", got, want)
+	}
+
+	// renaming to an existing name or to nothing is rejected on Apply
+	name.SetText("work")
+	if err := validateGistTargets(edited()); err == nil {
+		t.Error("duplicate name accepted")
+	}
+	name.SetText("  ")
+	if err := validateGistTargets(edited()); err == nil {
+		t.Error("empty name accepted")
+	}
+	name.SetText("personal")
+
+	// remove the middle one; the rows after it move up
+	_, remove := targetRow(t, editor, 1)
+	test.Tap(remove)
+	want = []GistTarget{{Name: "personal", ID: "abc"}, {Name: "home"}}
+	if got := edited(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after removing: %+v, want %+v", got, want)
+	}
+	if name, _ := targetRow(t, editor, 1); name.Text != "home" {
+		t.Errorf("second row shows %q", name.Text)
+	}
+	if err := validateGistTargets(edited()); err != nil {
+		t.Error(err)
+	}
+	// the editor works on a copy until Apply
+	if am.config.GistTargets[0].Name != defaultGistTarget || len(am.config.GistTargets) != 1 {
+		t.Errorf("config changed: %+v", am.config.GistTargets)
+	}
+}
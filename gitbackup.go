@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultGitFile is the path of the alias file inside the repository
+const defaultGitFile = "bash_aliases"
+
+// gitProvider backs up into a local git repository, one commit per backup.
+// With sync enabled it pulls before every backup and restore and pushes
+// after every backup, also when there was nothing new to commit.
+type gitProvider struct {
+	repo string
+	file string
+	sync bool
+}
+
+// openGitProvider opens the repository configured in Settings
+func openGitProvider(am *AliasManager, fn func(BackupProvider)) {
+	if am.config.GitRepo == "" {
+		dialog.ShowInformation("Backup", "Choose the git repository to back up to in Settings → Backup.", am.window)
+		return
+	}
+	home, _ := homeDir()
+	p := &gitProvider{repo: expandPath(am.config.GitRepo, home), file: am.config.GitFile, sync: am.config.GitSync}
+	if p.file == "" {
+		p.file = defaultGitFile
+	}
+	am.runInBackground("Git repository", "Opening "+p.repo+"…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+		_, err := p.git(ctx, "rev-parse", "--is-inside-work-tree")
+		return err
+	}, func(err error) {
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("%s is not a git repository: %v", p.repo, err), am.window)
+			return
+		}
+		fn(p)
+	})
+}
+
+func (p *gitProvider) Name() string {
+	return "git repository " + filepath.Base(p.repo)
+}
+
+// git runs a git command in the repository and returns its output
+func (p *gitProvider) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", p.repo}, args...)...)
+	// fail instead of waiting for a password nobody can type
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
+
+// pull merges the remote branch when sync is enabled. A repository without
+// an upstream branch is left alone. A merge that fails, e.g. on a conflict,
+// is aborted so the repository is not left half-merged.
+func (p *gitProvider) pull(ctx context.Context) error {
+	if !p.sync {
+		return nil
+	}
+	if _, err := p.git(ctx, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		return nil
+	}
+	if _, err := p.git(ctx, "pull", "--no-rebase", "--no-edit"); err != nil {
+		// not cancelled with ctx, which may be what stopped the merge; fails
+		// harmlessly when the pull stopped before merging
+		p.git(context.Background(), "merge", "--abort")
+		return err
+	}
+	return nil
+}
+
+func (p *gitProvider) Save(ctx context.Context, content []byte) error {
+	if err := p.pull(ctx); err != nil {
+		return err
+	}
+	path := filepath.Join(p.repo, p.file)
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil && bytes.Equal(old, content) {
+		// nothing to commit, but an earlier push may have failed
+		return p.push(ctx)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, content, 0644); err != nil {
+		return err
+	}
+	if _, err := p.git(ctx, "add", "--", p.file); err != nil {
+		return err
+	}
+	if _, err := p.git(ctx, "commit", "-m", gitCommitMessage(old, content), "--", p.file); err != nil {
+		return err
+	}
+	return p.push(ctx)
+}
+
+// push sends the commits the remote branch does not have yet when sync is
+// enabled, including ones an earlier failed push left behind. A branch
+// without an upstream is pushed to origin and tracks it from then on.
+func (p *gitProvider) push(ctx context.Context) error {
+	if !p.sync {
+		return nil
+	}
+	if _, err := p.git(ctx, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		_, err = p.git(ctx, "push", "--set-upstream", "origin", "HEAD")
+		return err
+	}
+	out, err := p.git(ctx, "rev-list", "--count", "@{upstream}..HEAD")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(out)) == "0" {
+		return nil
+	}
+	_, err = p.git(ctx, "push")
+	return err
+}
+
+func (p *gitProvider) Load(ctx context.Context) ([]byte, error) {
+	if err := p.pull(ctx); err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(p.repo, p.file))
+	if os.IsNotExist(err) {
+		return nil, errNoBackup
+	}
+	return content, err
+}
+
+func (p *gitProvider) Revisions(ctx context.Context, limit int) ([]BackupRevision, error) {
+	if err := p.pull(ctx); err != nil {
+		return nil, err
+	}
+	out, err := p.git(ctx, "log", fmt.Sprintf("-n%d", limit), "--format=%H%x09%cI", "--", p.file)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []BackupRevision
+	for _, line := range splitLines(string(out)) {
+		hash, date, _ := strings.Cut(line, "\t")
+		content, err := p.git(ctx, "show", hash+":"+filepath.ToSlash(p.file))
+		if err != nil {
+			// the file was deleted in this commit
+			continue
+		}
+		when := date
+		if t, err := time.Parse(time.RFC3339, date); err == nil {
+			when = t.Local().Format("2006-01-02 15:04")
+		}
+		revisions = append(revisions, BackupRevision{
+			Version: hash,
+			Time:    when,
+			Content: content,
+			Aliases: aliasCount(content),
+		})
+	}
+	return revisions, nil
+}
+
+// gitCommitMessage describes which aliases a backup adds, removes or changes
+func gitCommitMessage(old, content []byte) string {
+	if isEncryptedBackup(content) {
+		return "Update encrypted aliases"
+	}
+	before, _ := parseAliasContent(old)
+	after, _ := parseAliasContent(content)
+	names := map[string][]string{}
+	for _, c := range diffAliases(before, after) {
+		names[c.Kind] = append(names[c.Kind], c.Name)
+	}
+	var parts []string
+	for _, k := range []struct{ kind, verb string }{{changeAdded, "add"}, {changeChanged, "change"}, {changeRemoved, "remove"}} {
+		if len(names[k.kind]) > 0 {
+			parts = append(parts, k.verb+" "+strings.Join(names[k.kind], ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "Update aliases"
+	}
+	msg := strings.Join(parts, "; ")
+	return strings.ToUpper(msg[:1]) + msg[1:]
+}
+
+// gitSettings configures the repository, file and remote sync
+func gitSettings(am *AliasManager) fyne.CanvasObject {
+	repoEntry := widget.NewEntry()
+	repoEntry.SetPlaceHolder("~/dotfiles")
+	repoEntry.SetText(am.config.GitRepo)
+	fileEntry := widget.NewEntry()
+	fileEntry.SetPlaceHolder(defaultGitFile)
+	fileEntry.SetText(am.config.GitFile)
+	syncCheck := widget.NewCheck("Pull before restoring and push after backing up", nil)
+	syncCheck.SetChecked(am.config.GitSync)
+
+	applyBtn := widget.NewButton("Apply", func() {
+		file := strings.TrimSpace(fileEntry.Text)
+		if filepath.IsAbs(file) || strings.HasPrefix(filepath.Clean(file), "..") {
+			dialog.ShowError(fmt.Errorf("The file must be a path inside the repository"), am.window)
+			return
+		}
+		am.config.GitRepo = strings.TrimSpace(repoEntry.Text)
+		am.config.GitFile = file
+		am.config.GitSync = syncCheck.Checked
+		if err := am.saveConfig(); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+	})
+	form := widget.NewForm(
+		widget.NewFormItem("Repository", repoEntry),
+		widget.NewFormItem("File in repository", fileEntry),
+		widget.NewFormItem("Remote", syncCheck),
+	)
+	return container.NewVBox(form, container.NewHBox(applyBtn))
+}
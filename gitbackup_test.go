@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// runGit runs git in dir, failing the test on errors
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestGitPullAbortsConflictingMerge(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "t")
+	t.Setenv("GIT_AUTHOR_EMAIL", "t@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "t")
+	t.Setenv("GIT_COMMITTER_EMAIL", "t@example.com")
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	runGit(t, dir, "init", "-q", "--bare", remote)
+	other := filepath.Join(dir, "other")
+	runGit(t, dir, "clone", "-q", remote, other)
+	os.WriteFile(filepath.Join(other, defaultGitFile), []byte("alias ll='ls -l'\n"), 0644)
+	runGit(t, other, "add", defaultGitFile)
+	runGit(t, other, "commit", "-q", "-m", "first")
+	runGit(t, other, "push", "-q", "origin", "HEAD")
+
+	local := filepath.Join(dir, "local")
+	runGit(t, dir, "clone", "-q", remote, local)
+	os.WriteFile(filepath.Join(local, defaultGitFile), []byte("alias ll='ls -lh'\n"), 0644)
+	runGit(t, local, "commit", "-q", "-am", "local change")
+	os.WriteFile(filepath.Join(other, defaultGitFile), []byte("alias ll='ls -la'\n"), 0644)
+	runGit(t, other, "commit", "-q", "-am", "remote change")
+	runGit(t, other, "push", "-q", "origin", "HEAD")
+
+	p := &gitProvider{repo: local, file: defaultGitFile, sync: true}
+	if err := p.Save(context.Background(), []byte("alias ll='ls -lht'\n")); err == nil {
+		t.Fatal("expected the conflicting pull to fail")
+	}
+	if _, err := os.Stat(filepath.Join(local, ".git", "MERGE_HEAD")); !os.IsNotExist(err) {
+		t.Errorf("merge was left in progress: %v", err)
+	}
+}
+
+func TestGitSaveRetriesFailedPush(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "t")
+	t.Setenv("GIT_AUTHOR_EMAIL", "t@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "t")
+	t.Setenv("GIT_COMMITTER_EMAIL", "t@example.com")
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	runGit(t, dir, "init", "-q", "--bare", remote)
+	local := filepath.Join(dir, "local")
+	runGit(t, dir, "clone", "-q", remote, local)
+	os.WriteFile(filepath.Join(local, defaultGitFile), []byte("alias ll='ls -l'\n"), 0644)
+	runGit(t, local, "add", defaultGitFile)
+	runGit(t, local, "commit", "-q", "-m", "first")
+	runGit(t, local, "push", "-q", "--set-upstream", "origin", "HEAD")
+
+	// the remote rejects pushes but can still be pulled from
+	hook := filepath.Join(remote, "hooks", "pre-receive")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := &gitProvider{repo: local, file: defaultGitFile, sync: true}
+	content := []byte("alias ll='ls -la'\n")
+	if err := p.Save(context.Background(), content); err == nil {
+		t.Fatal("expected the push to fail")
+	}
+
+	// the next backup of the same aliases has nothing to commit but still
+	// sends the commit left behind
+	if err := os.Remove(hook); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(context.Background(), content); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("git", "-C", remote, "show", "HEAD:"+defaultGitFile).Output()
+	if err != nil || string(out) != string(content) {
+		t.Errorf("remote has %q, %v; want %q", out, err, content)
+	}
+	runGit(t, local, "diff", "--quiet", "@{upstream}", "HEAD")
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	// defaultGiteaURL is used when no Gitea server is configured
+	defaultGiteaURL = "https://gitea.com"
+	// defaultGiteaRepo is the backup repository created under the user
+	defaultGiteaRepo = "bash-aliases"
+)
+
+// giteaProvider backs up to a file in a private Gitea repository. Gitea has
+// no gists, so the repository takes their place: it is created on the first
+// backup and every backup is a commit.
+type giteaProvider struct {
+	api *forgeClient
+	// repo is "owner/name", or just "name" for a repository of the user
+	repo string
+}
+
+// giteaContent is the part of the contents API response used here
+type giteaContent struct {
+	SHA     string `json:"sha"`
+	Content string `json:"content"`
+}
+
+// giteaCommit is the part of the commits API response used here
+type giteaCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// openGiteaProvider opens the Gitea provider once the Gitea token is available
+func openGiteaProvider(am *AliasManager, fn func(BackupProvider)) {
+	am.withServiceToken(serviceGitea, func(token string) {
+		repo := strings.Trim(strings.TrimSpace(am.config.GiteaRepo), "/")
+		if repo == "" {
+			repo = defaultGiteaRepo
+		}
+		fn(&giteaProvider{
+			api: &forgeClient{
+				api:    forgeURL(am.config.GiteaURL, defaultGiteaURL) + "/api/v1",
+				header: "Authorization",
+				value:  "token " + token,
+			},
+			repo: repo,
+		})
+	})
+}
+
+func (g *giteaProvider) Name() string {
+	return "Gitea repository " + g.repo
+}
+
+// explain turns authentication failures into guidance
+func (g *giteaProvider) explain(err error) error {
+	switch {
+	case isStatus(err, 401):
+		return fmt.Errorf("invalid Gitea token: %v", err)
+	case isStatus(err, 403):
+		return fmt.Errorf("access denied (%v). Ensure your Gitea token has read and write access to repositories", err)
+	}
+	return err
+}
+
+// resolve fills in the owner when only a repository name is configured
+func (g *giteaProvider) resolve(ctx context.Context) error {
+	if strings.Contains(g.repo, "/") {
+		return nil
+	}
+	login, err := g.login(ctx)
+	if err != nil {
+		return err
+	}
+	g.repo = login + "/" + g.repo
+	return nil
+}
+
+// login returns the name of the user the token belongs to
+func (g *giteaProvider) login(ctx context.Context) (string, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := g.api.call(ctx, "GET", "/user", nil, &user); err != nil {
+		return "", g.explain(err)
+	}
+	return user.Login, nil
+}
+
+// path returns the API path of the repository followed by the given parts
+func (g *giteaProvider) path(parts ...string) string {
+	owner, name, _ := strings.Cut(g.repo, "/")
+	p := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+	for _, part := range parts {
+		p += "/" + part
+	}
+	return p
+}
+
+// createRepo creates the private backup repository under the user, or under
+// the organization when another owner is configured
+func (g *giteaProvider) createRepo(ctx context.Context) error {
+	owner, name, _ := strings.Cut(g.repo, "/")
+	login, err := g.login(ctx)
+	if err != nil {
+		return err
+	}
+	org := !strings.EqualFold(owner, login)
+	path := "/user/repos"
+	if org {
+		path = "/orgs/" + url.PathEscape(owner) + "/repos"
+	}
+	body := map[string]interface{}{
+		"name":        name,
+		"private":     true,
+		"description": "Backup of the alias file by Bash Alias Manager",
+		"auto_init":   true,
+	}
+	if err := g.api.call(ctx, "POST", path, body, nil); err != nil {
+		if org && isStatus(err, 404) {
+			// another user, or an organization the token cannot see
+			return fmt.Errorf("%s is not an organization you belong to; create the repository %s on the server first", owner, g.repo)
+		}
+		return fmt.Errorf("creating the repository failed: %v", g.explain(err))
+	}
+	return nil
+}
+
+func (g *giteaProvider) Save(ctx context.Context, content []byte) error {
+	if err := g.resolve(ctx); err != nil {
+		return err
+	}
+	if err := g.api.call(ctx, "GET", g.path(), nil, nil); err != nil {
+		if !isStatus(err, 404) {
+			return g.explain(err)
+		}
+		if err := g.createRepo(ctx); err != nil {
+			return err
+		}
+	}
+
+	var existing giteaContent
+	err := g.api.call(ctx, "GET", g.path("contents", gistFileName), nil, &existing)
+	if err != nil && !isStatus(err, 404) {
+		return g.explain(err)
+	}
+	old, _ := base64.StdEncoding.DecodeString(existing.Content)
+	body := map[string]interface{}{
+		"content": base64.StdEncoding.EncodeToString(content),
+		"message": gitCommitMessage(old, content),
+	}
+	method := "POST"
+	if err == nil {
+		if string(old) == string(content) {
+			return nil
+		}
+		body["sha"] = existing.SHA
+		method = "PUT"
+	}
+	if err := g.api.call(ctx, method, g.path("contents", gistFileName), body, nil); err != nil {
+		return fmt.Errorf("updating the repository failed: %v", g.explain(err))
+	}
+	return nil
+}
+
+// raw returns the backup file at ref, or at the default branch when ref is empty
+func (g *giteaProvider) raw(ctx context.Context, ref string) ([]byte, error) {
+	path := g.path("raw", gistFileName)
+	if ref != "" {
+		path += "?ref=" + url.QueryEscape(ref)
+	}
+	content, err := g.api.request(ctx, "GET", path, nil)
+	if isStatus(err, 404) {
+		return nil, errNoBackup
+	}
+	if err != nil {
+		return nil, g.explain(err)
+	}
+	return content, nil
+}
+
+func (g *giteaProvider) Load(ctx context.Context) ([]byte, error) {
+	if err := g.resolve(ctx); err != nil {
+		return nil, err
+	}
+	return g.raw(ctx, "")
+}
+
+func (g *giteaProvider) Revisions(ctx context.Context, limit int) ([]BackupRevision, error) {
+	if err := g.resolve(ctx); err != nil {
+		return nil, err
+	}
+	var commits []giteaCommit
+	query := fmt.Sprintf("?path=%s&limit=%d", url.QueryEscape(gistFileName), limit)
+	if err := g.api.call(ctx, "GET", g.path("commits")+query, nil, &commits); err != nil {
+		if isStatus(err, 404) || isStatus(err, 409) {
+			// no repository, or an empty one
+			return nil, errNoBackup
+		}
+		return nil, g.explain(err)
+	}
+	var revisions []BackupRevision
+	for _, c := range commits {
+		content, err := g.raw(ctx, c.SHA)
+		if err == errNoBackup {
+			// the initial commit, or one deleting the file
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, BackupRevision{
+			Version: c.SHA,
+			Time:    c.Commit.Committer.Date.Local().Format("2006-01-02 15:04"),
+			Content: content,
+			Aliases: aliasCount(content),
+		})
+	}
+	return revisions, nil
+}
+
+// giteaSettings configures the Gitea server and repository. Changing the
+// server forgets the stored token, which belongs to the old one.
+func giteaSettings(am *AliasManager) fyne.CanvasObject {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder(defaultGiteaURL)
+	urlEntry.SetText(am.config.GiteaURL)
+	repoEntry := widget.NewEntry()
+	repoEntry.SetPlaceHolder(defaultGiteaRepo + " or owner/" + defaultGiteaRepo)
+	repoEntry.SetText(am.config.GiteaRepo)
+
+	applyBtn := widget.NewButton("Apply", func() {
+		server := strings.TrimSpace(urlEntry.Text)
+		if forgeURL(server, defaultGiteaURL) != forgeURL(am.config.GiteaURL, defaultGiteaURL) {
+			if err := am.forgetToken(serviceGitea); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to remove the Gitea token: %v", err), am.window)
+			}
+		}
+		am.config.GiteaURL = server
+		am.config.GiteaRepo = strings.TrimSpace(repoEntry.Text)
+		if err := am.saveConfig(); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+	})
+	form := widget.NewForm(
+		widget.NewFormItem("Gitea server", urlEntry),
+		widget.NewFormItem("Repository", repoEntry),
+	)
+	return container.NewVBox(form, container.NewHBox(applyBtn))
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitea is the part of the Gitea API the provider uses. Repositories are
+// lists of commits holding the backup file, or nil for the initial commit.
+type fakeGitea struct {
+	login string
+	orgs  map[string]bool
+	mu    sync.Mutex
+	repos map[string][][]byte
+	// created records the creation requests, e.g. "POST /orgs/team/repos"
+	created []string
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "token secret" {
+		w.WriteHeader(401)
+		fmt.Fprint(w, `{"message":"token is required"}`)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	if path == "/user" {
+		json.NewEncoder(w).Encode(map[string]string{"login": f.login})
+		return
+	}
+	if r.Method == "POST" && strings.HasSuffix(path, "/repos") {
+		owner := f.login
+		if org, ok := strings.CutPrefix(path, "/orgs/"); ok {
+			owner = strings.TrimSuffix(org, "/repos")
+			if !f.orgs[owner] {
+				w.WriteHeader(404)
+				return
+			}
+		} else if path != "/user/repos" {
+			w.WriteHeader(404)
+			return
+		}
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.repos[strings.ToLower(owner+"/"+body.Name)] = [][]byte{nil}
+		f.created = append(f.created, "POST "+path)
+		w.WriteHeader(201)
+		fmt.Fprint(w, "{}")
+		return
+	}
+
+	rest, ok := strings.CutPrefix(path, "/repos/")
+	parts := strings.SplitN(rest, "/", 3)
+	if !ok || len(parts) < 2 {
+		w.WriteHeader(404)
+		return
+	}
+	// owner and repository names are case-insensitive
+	key := strings.ToLower(parts[0] + "/" + parts[1])
+	commits, ok := f.repos[key]
+	if !ok {
+		w.WriteHeader(404)
+		fmt.Fprint(w, `{"message":"repository not found"}`)
+		return
+	}
+	latest := commits[len(commits)-1]
+	sha := strconv.Itoa(len(commits))
+	switch {
+	case len(parts) == 2:
+		fmt.Fprint(w, "{}")
+	case parts[2] == "contents/"+gistFileName && r.Method == "GET":
+		if latest == nil {
+			w.WriteHeader(404)
+			return
+		}
+		json.NewEncoder(w).Encode(giteaContent{SHA: sha, Content: base64.StdEncoding.EncodeToString(latest)})
+	case parts[2] == "contents/"+gistFileName:
+		var body struct {
+			Content string `json:"content"`
+			SHA     string `json:"sha"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		// POST creates the file, PUT updates the version it names
+		if (r.Method == "POST") != (latest == nil) || (r.Method == "PUT" && body.SHA != sha) || body.Message == "" {
+			w.WriteHeader(422)
+			return
+		}
+		content, _ := base64.StdEncoding.DecodeString(body.Content)
+		f.repos[key] = append(commits, content)
+		w.WriteHeader(201)
+		fmt.Fprint(w, "{}")
+	case parts[2] == "raw/"+gistFileName:
+		i := len(commits)
+		if ref := r.URL.Query().Get("ref"); ref != "" {
+			i, _ = strconv.Atoi(ref)
+		}
+		if i < 1 || i > len(commits) || commits[i-1] == nil {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write(commits[i-1])
+	case parts[2] == "commits":
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var list []giteaCommit
+		for i := len(commits); i > 0 && len(list) < limit; i-- {
+			if commits[i-1] == nil {
+				continue
+			}
+			var c giteaCommit
+			c.SHA = strconv.Itoa(i)
+			c.Commit.Committer.Date = time.Date(2024, 1, i, 12, 0, 0, 0, time.UTC)
+			list = append(list, c)
+		}
+		json.NewEncoder(w).Encode(list)
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+// newGiteaTest returns a provider for repo on a fake server of the user "me"
+func newGiteaTest(t *testing.T, repo string) (*giteaProvider, *fakeGitea) {
+	fake := &fakeGitea{login: "me", orgs: map[string]bool{"team": true}, repos: map[string][][]byte{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	p := &giteaProvider{
+		api:  &forgeClient{api: srv.URL + "/api/v1", header: "Authorization", value: "token secret"},
+		repo: repo,
+	}
+	return p, fake
+}
+
+func TestGiteaProvider(t *testing.T) {
+	ctx := context.Background()
+	p, fake := newGiteaTest(t, defaultGiteaRepo)
+	if _, err := p.Load(ctx); !errors.Is(err, errNoBackup) {
+		t.Fatalf("Load before the first backup: %v, want errNoBackup", err)
+	}
+	if revs, err := p.Revisions(ctx, 10); !errors.Is(err, errNoBackup) {
+		t.Fatalf("Revisions before the first backup = %v, %v", revs, err)
+	}
+
+	backups := []string{"alias a='1'\n", "alias a='1'\nalias b='2'\n", "alias c='3'\n"}
+	for _, b := range backups {
+		if err := p.Save(ctx, []byte(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the same content again adds no commit
+	if err := p.Save(ctx, []byte(backups[2])); err != nil {
+		t.Fatal(err)
+	}
+	if p.repo != "me/"+defaultGiteaRepo || len(fake.created) != 1 || fake.created[0] != "POST /user/repos" {
+		t.Errorf("repository %q created by %q", p.repo, fake.created)
+	}
+	if n := len(fake.repos["me/"+defaultGiteaRepo]); n != 4 {
+		t.Errorf("%d commits, want the initial one and three backups", n)
+	}
+
+	content, err := p.Load(ctx)
+	if err != nil || string(content) != backups[2] {
+		t.Fatalf("Load = %q, %v; want the latest backup", content, err)
+	}
+	revs, err := p.Revisions(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 || string(revs[0].Content) != backups[2] || string(revs[1].Content) != backups[1] || revs[1].Aliases != 2 {
+		t.Errorf("Revisions = %+v", revs)
+	}
+	// the initial commit has no backup and is left out
+	if revs, err := p.Revisions(ctx, 10); err != nil || len(revs) != 3 {
+		t.Errorf("all Revisions = %+v, %v", revs, err)
+	}
+}
+
+func TestGiteaCreateRepo(t *testing.T) {
+	for _, tt := range []struct {
+		repo    string
+		created string
+		err     string
+	}{
+		{repo: "aliases", created: "POST /user/repos"},
+		{repo: "Me/aliases", created: "POST /user/repos"},
+		{repo: "team/aliases", created: "POST /orgs/team/repos"},
+		{repo: "someone/aliases", err: "create the repository someone/aliases"},
+	} {
+		p, fake := newGiteaTest(t, tt.repo)
+		err := p.Save(context.Background(), []byte("alias a='1'\n"))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.repo, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.repo, err)
+			continue
+		}
+		if len(fake.created) != 1 || fake.created[0] != tt.created {
+			t.Errorf("%s: created by %q, want %q", tt.repo, fake.created, tt.created)
+		}
+	}
+}
+
+func TestGiteaInvalidToken(t *testing.T) {
+	p, _ := newGiteaTest(t, defaultGiteaRepo)
+	p.api.value = "token wrong"
+	if _, err := p.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid Gitea token") {
+		t.Errorf("Load with a wrong token: %v", err)
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// OAuthClientID is the client ID of the GitHub OAuth app used for signing in
+// with the device flow. It is set at build time via
+// -ldflags "-X main.OAuthClientID=..."; Config.GitHubClientID overrides it.
+var OAuthClientID = ""
+
+// githubClientID returns the OAuth client ID, or "" when signing in through
+// the browser is not available
+func (c Config) githubClientID() string {
+	if id := strings.TrimSpace(c.GitHubClientID); id != "" {
+		return id
+	}
+	return OAuthClientID
+}
+
+// githubWebURL returns the web address belonging to the configured API,
+// ending in a slash: github.com, the root of GitHub Enterprise Server, or
+// the API URL itself for other (test) servers
+func (c Config) githubWebURL() string {
+	api, _ := c.githubURLs()
+	switch {
+	case api == defaultGitHubAPIURL:
+		return "https://github.com/"
+	case strings.HasSuffix(api, "/api/v3/"):
+		return strings.TrimSuffix(api, "api/v3/")
+	}
+	return api
+}
+
+// pollUnit is the unit of the intervals and lifetime GitHub sends for a
+// device code; tests shorten it
+var pollUnit = time.Second
+
+// errCodeExpired is returned when the device code was not entered in time
+var errCodeExpired = errors.New("the code expired before it was entered")
+
+// deviceCode is the response starting the OAuth device flow
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// oauthPost posts a form to the GitHub login endpoint path and decodes the
+// JSON answer into out
+func oauthPost(ctx context.Context, web, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", web+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// requestDeviceCode starts the device flow for the gist scope
+func requestDeviceCode(ctx context.Context, web, clientID string) (*deviceCode, error) {
+	var dc deviceCode
+	err := oauthPost(ctx, web, "login/device/code", url.Values{"client_id": {clientID}, "scope": {"gist"}}, &dc)
+	if err != nil {
+		return nil, fmt.Errorf("starting the sign-in failed: %v", err)
+	}
+	if dc.DeviceCode == "" {
+		return nil, fmt.Errorf("starting the sign-in failed: device flow is not enabled for this OAuth app")
+	}
+	return &dc, nil
+}
+
+// pollDeviceToken waits until the user authorized the device code and
+// returns the access token
+func pollDeviceToken(ctx context.Context, web, clientID string, dc *deviceCode) (string, error) {
+	interval := time.Duration(dc.Interval) * pollUnit
+	if interval <= 0 {
+		interval = 5 * pollUnit
+	}
+	expires := time.Duration(dc.ExpiresIn) * pollUnit
+	if expires <= 0 {
+		expires = 900 * pollUnit
+	}
+	ctx, cancel := context.WithTimeout(ctx, expires)
+	defer cancel()
+	form := url.Values{
+		"client_id":   {clientID},
+		"device_code": {dc.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return "", errCodeExpired
+			}
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+		var result struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+			Interval    int    `json:"interval"`
+		}
+		if err := oauthPost(ctx, web, "login/oauth/access_token", form, &result); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return "", errCodeExpired
+			}
+			return "", err
+		}
+		switch result.Error {
+		case "":
+			return result.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			if result.Interval > 0 {
+				interval = time.Duration(result.Interval) * pollUnit
+			} else {
+				interval += 5 * pollUnit
+			}
+		case "expired_token":
+			return "", errCodeExpired
+		case "access_denied":
+			return "", fmt.Errorf("the sign-in was cancelled on GitHub")
+		default:
+			return "", fmt.Errorf("%s: %s", result.Error, result.Description)
+		}
+	}
+}
+
+// checkGitHubScopes verifies the client's token and that it grants the gist
+// scope. Fine-grained tokens report no scopes and are accepted as they are.
+func checkGitHubScopes(ctx context.Context, client *github.Client, web string) error {
+	_, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == 401 {
+			return fmt.Errorf("the GitHub token is invalid or expired")
+		}
+		return fmt.Errorf("checking the GitHub token failed: %w", err)
+	}
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return nil
+	}
+	var scopes []string
+	for _, s := range strings.Split(strings.Join(header, ","), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+		if s == "gist" {
+			return nil
+		}
+	}
+	granted := strings.Join(scopes, ", ")
+	if granted == "" {
+		granted = "none"
+	}
+	return fmt.Errorf("the GitHub token lacks the 'gist' scope (granted: %s). Create a token with the 'gist' scope at %ssettings/tokens", granted, web)
+}
+
+// checkGitHubToken runs checkGitHubScopes for a token before it is stored
+func checkGitHubToken(ctx context.Context, token string, config Config) error {
+	client, err := newGitHubClient(token, config)
+	if err != nil {
+		return err
+	}
+	return checkGitHubScopes(ctx, client, config.githubWebURL())
+}
+
+// signInGitHub offers signing in through the browser when an OAuth client ID
+// is available, or pasting a personal access token, then stores the token
+// and calls fn
+func (am *AliasManager) signInGitHub(fn func(string)) {
+	clientID := am.config.githubClientID()
+	if clientID == "" {
+		am.promptToken(serviceGitHub, fn)
+		return
+	}
+	var d dialog.Dialog
+	browserBtn := widget.NewButton("Sign in with browser", func() {
+		d.Hide()
+		am.deviceSignIn(clientID, fn)
+	})
+	browserBtn.Importance = widget.HighImportance
+	tokenBtn := widget.NewButton("Paste a personal access token", func() {
+		d.Hide()
+		am.promptToken(serviceGitHub, fn)
+	})
+	d = dialog.NewCustom("Sign in to GitHub", "Cancel", container.NewVBox(
+		widget.NewLabel("Gist backups need access to your GitHub account."),
+		browserBtn,
+		tokenBtn,
+	), am.window)
+	d.Show()
+}
+
+// deviceSignIn runs the OAuth device flow: it shows the code to enter on
+// GitHub and polls until the user approved the sign-in
+func (am *AliasManager) deviceSignIn(clientID string, fn func(string)) {
+	web := am.config.githubWebURL()
+	var dc *deviceCode
+	am.runInBackground("Sign in to GitHub", "Contacting GitHub…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+		var err error
+		dc, err = requestDeviceCode(ctx, web, clientID)
+		return err
+	}, func(err error) {
+		if err != nil {
+			dialog.ShowError(err, am.window)
+			return
+		}
+		am.awaitDeviceSignIn(web, clientID, dc, fn)
+	})
+}
+
+// awaitDeviceSignIn shows the code of a started device flow and waits for
+// the user to approve it
+func (am *AliasManager) awaitDeviceSignIn(web, clientID string, dc *deviceCode, fn func(string)) {
+	config := am.config
+	ctx, cancel := context.WithCancel(context.Background())
+
+	codeLabel := widget.NewLabelWithStyle(dc.UserCode, fyne.TextAlignCenter, fyne.TextStyle{Bold: true, Monospace: true})
+	copyBtn := widget.NewButton("Copy code", func() {
+		am.window.Clipboard().SetContent(dc.UserCode)
+	})
+	var link fyne.CanvasObject = widget.NewLabel(dc.VerificationURI)
+	if u, err := url.Parse(dc.VerificationURI); err == nil {
+		link = widget.NewHyperlink(dc.VerificationURI, u)
+	}
+	status := widget.NewLabel("Waiting for you to approve the sign-in…")
+	d := dialog.NewCustom("Sign in to GitHub", "Cancel", container.NewVBox(
+		widget.NewLabel("Open the address below and enter this code:"),
+		link,
+		codeLabel,
+		container.NewHBox(copyBtn),
+		widget.NewProgressBarInfinite(),
+		status,
+	), am.window)
+	d.SetOnClosed(cancel)
+	d.Show()
+	if u, err := url.Parse(dc.VerificationURI); err == nil {
+		fyne.CurrentApp().OpenURL(u)
+	}
+
+	go func() {
+		token, err := pollDeviceToken(ctx, web, clientID, dc)
+		if err == nil {
+			err = checkGitHubToken(ctx, token, config)
+		}
+		if ctx.Err() == context.Canceled {
+			// closed by the user
+			return
+		}
+		am.onUI(func() {
+			d.Hide()
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to sign in to GitHub: %v", err), am.window)
+				return
+			}
+			am.storeToken(serviceGitHub, token, fn)
+		})
+	}()
+}
+
+// revokeGitHubToken asks GitHub to revoke token through its credential
+// revocation API, which needs no client secret and works for OAuth and
+// personal access tokens alike
+func revokeGitHubToken(ctx context.Context, api, token string) error {
+	body, err := json.Marshal(map[string][]string{"credentials": {token}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", api+"credentials/revoke", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 202 && resp.StatusCode != 200 {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// signOutGitHub revokes the GitHub token on GitHub and forgets it. A token
+// protected by a passphrase is unlocked first. When it cannot be revoked,
+// e.g. on a GitHub Enterprise Server without the revocation API, it is still
+// forgotten and the user is pointed to the GitHub page where access can be
+// revoked. done is called once signed out.
+func (am *AliasManager) signOutGitHub(done func()) {
+	dialog.ShowConfirm("Sign out", "Revoke the GitHub token and remove it from this computer?", func(ok bool) {
+		if !ok {
+			return
+		}
+		token := am.sessionToken(serviceGitHub)
+		if token != "" || am.tokenStorage(serviceGitHub) != tokenStorageFile {
+			am.revokeAndForget(token, done)
+			return
+		}
+		home, err := homeDir()
+		if err != nil {
+			dialog.ShowError(err, am.window)
+			return
+		}
+		am.askPassphraseOr("Unlock GitHub token", "Enter the passphrase protecting your GitHub token to revoke it on GitHub.", false, func(passphrase string) {
+			token, err := readTokenFile(home, serviceGitHub, passphrase)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to decrypt the GitHub token: %v\n", err)
+			}
+			am.revokeAndForget(token, done)
+		}, func() { am.revokeAndForget("", done) })
+	}, am.window)
+}
+
+// revokeAndForget revokes token, read from the keyring when empty, and
+// removes it from this computer
+func (am *AliasManager) revokeAndForget(token string, done func()) {
+	api, _ := am.config.githubURLs()
+	storage := am.tokenStorage(serviceGitHub)
+	am.runInBackground("Sign out", "Revoking the GitHub token…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+		if token == "" && storage == tokenStorageKeyring {
+			var err error
+			if token, err = keyringGet(serviceGitHub); err != nil {
+				return err
+			}
+		}
+		if token == "" {
+			return fmt.Errorf("the token could not be read")
+		}
+		return revokeGitHubToken(ctx, api, token)
+	}, func(revokeErr error) {
+		if err := am.forgetToken(serviceGitHub); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to remove the GitHub token: %v", err), am.window)
+			return
+		}
+		done()
+		if revokeErr == nil {
+			dialog.ShowInformation("Signed out", "The GitHub token was revoked and removed from this computer.", am.window)
+			return
+		}
+		web := am.config.githubWebURL()
+		page := web + "settings/tokens"
+		if id := am.config.githubClientID(); id != "" {
+			page = web + "settings/connections/applications/" + id
+		}
+		msg := widget.NewLabel(fmt.Sprintf("The token was removed from this computer, but GitHub could not revoke it: %v\n\nTo revoke it on GitHub, open:", revokeErr))
+		msg.Wrapping = fyne.TextWrapWord
+		var link fyne.CanvasObject = widget.NewLabel(page)
+		if u, err := url.Parse(page); err == nil {
+			link = widget.NewHyperlink(page, u)
+		}
+		d := dialog.NewCustom("Signed out", "Close", container.NewVBox(msg, link), am.window)
+		d.Resize(fyne.NewSize(450, 0))
+		d.Show()
+	})
+}
+
+// githubAccount builds the sign-in and sign-out buttons of the Gist settings
+func (am *AliasManager) githubAccount() fyne.CanvasObject {
+	box := container.NewHBox()
+	var refresh func()
+	refresh = func() {
+		signedIn := am.config.GitHubToken != "" || am.config.TokenStorage != ""
+		if signedIn {
+			box.Objects = []fyne.CanvasObject{widget.NewButton("Sign out", func() {
+				am.signOutGitHub(refresh)
+			})}
+		} else {
+			box.Objects = []fyne.CanvasObject{widget.NewButton("Sign in", func() {
+				am.withToken(refresh)
+			})}
+		}
+		box.Refresh()
+	}
+	refresh()
+	return box
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRevokeGitHubToken(t *testing.T) {
+	var revoked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/credentials/revoke" {
+			w.WriteHeader(404)
+			return
+		}
+		var body struct {
+			Credentials []string `json:"credentials"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		revoked = append(revoked, body.Credentials...)
+		w.WriteHeader(202)
+	}))
+	defer srv.Close()
+
+	if err := revokeGitHubToken(context.Background(), srv.URL+"/", "gho_secret"); err != nil {
+		t.Fatal(err)
+	}
+	if len(revoked) != 1 || revoked[0] != "gho_secret" {
+		t.Errorf("revoked %q", revoked)
+	}
+	// a server without the revocation API
+	if err := revokeGitHubToken(context.Background(), srv.URL+"/api/v3/", "gho_secret"); err == nil {
+		t.Error("expected an error from a server without the revocation API")
+	}
+}
+
+// fakeDeviceFlow is the GitHub login endpoint of the device flow. Every poll
+// gets the next of its answers and is timed.
+type fakeDeviceFlow struct {
+	answers []string
+	mu      sync.Mutex
+	polls   []time.Time
+}
+
+func (f *fakeDeviceFlow) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.Form.Get("client_id") != "client" {
+		w.WriteHeader(400)
+		return
+	}
+	switch r.URL.Path {
+	case "/login/device/code":
+		fmt.Fprint(w, `{"device_code":"dev","user_code":"ABCD-1234","verification_uri":"https://github.com/login/device","expires_in":900,"interval":1}`)
+	case "/login/oauth/access_token":
+		if r.Form.Get("device_code") != "dev" {
+			w.WriteHeader(400)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.polls = append(f.polls, time.Now())
+		answer := f.answers[0]
+		if len(f.answers) > 1 {
+			f.answers = f.answers[1:]
+		}
+		fmt.Fprint(w, answer)
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	unit := pollUnit
+	pollUnit = 10 * time.Millisecond
+	defer func() { pollUnit = unit }()
+
+	const (
+		pending = `{"error":"authorization_pending"}`
+		token   = `{"access_token":"gho_token","token_type":"bearer","scope":"gist"}`
+	)
+	for _, tt := range []struct {
+		name    string
+		answers []string
+		token   string
+		err     error
+		errText string
+		// gaps are the least intervals between polls, in units
+		gaps []time.Duration
+	}{
+		{name: "authorized", answers: []string{pending, pending, token}, token: "gho_token", gaps: []time.Duration{1, 1}},
+		{
+			name:    "slow down to the given interval",
+			answers: []string{`{"error":"slow_down","interval":4}`, pending, token},
+			token:   "gho_token",
+			gaps:    []time.Duration{4, 4},
+		},
+		{
+			name:    "slow down by five seconds",
+			answers: []string{`{"error":"slow_down"}`, `{"error":"slow_down"}`, token},
+			token:   "gho_token",
+			gaps:    []time.Duration{6, 11},
+		},
+		{name: "expired", answers: []string{pending, `{"error":"expired_token"}`}, err: errCodeExpired},
+		{name: "denied", answers: []string{`{"error":"access_denied"}`}, errText: "cancelled"},
+		{name: "other error", answers: []string{`{"error":"unsupported_grant_type","error_description":"bad grant"}`}, errText: "unsupported_grant_type: bad grant"},
+	} {
+		fake := &fakeDeviceFlow{answers: tt.answers}
+		srv := httptest.NewServer(fake)
+		web := srv.URL + "/"
+		dc, err := requestDeviceCode(context.Background(), web, "client")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := pollDeviceToken(context.Background(), web, "client", dc)
+		srv.Close()
+		switch {
+		case tt.err != nil && !errors.Is(err, tt.err):
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		case tt.errText != "" && (err == nil || !strings.Contains(err.Error(), tt.errText)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.errText)
+		case tt.err == nil && tt.errText == "" && (err != nil || got != tt.token):
+			t.Errorf("%s: token %q, %v; want %q", tt.name, got, err, tt.token)
+		}
+		for i, gap := range tt.gaps {
+			if i+1 >= len(fake.polls) {
+				t.Errorf("%s: %d polls", tt.name, len(fake.polls))
+				break
+			}
+			if d := fake.polls[i+1].Sub(fake.polls[i]); d < gap*pollUnit {
+				t.Errorf("%s: poll %d after %v, want at least %v", tt.name, i+2, d, gap*pollUnit)
+			}
+		}
+	}
+}
+
+func TestDeviceFlowExpires(t *testing.T) {
+	unit := pollUnit
+	pollUnit = time.Millisecond
+	defer func() { pollUnit = unit }()
+	srv := httptest.NewServer(&fakeDeviceFlow{answers: []string{`{"error":"authorization_pending"}`}})
+	defer srv.Close()
+	// the code runs out while GitHub still waits for the user
+	dc := &deviceCode{DeviceCode: "dev", ExpiresIn: 30, Interval: 5}
+	if _, err := pollDeviceToken(context.Background(), srv.URL+"/", "client", dc); !errors.Is(err, errCodeExpired) {
+		t.Errorf("err = %v, want errCodeExpired", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pollDeviceToken(ctx, srv.URL+"/", "client", dc); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: err = %v", err)
+	}
+}
+
+func TestRequestDeviceCodeDisabled(t *testing.T) {
+	// GitHub answers 200 with an error when the app has no device flow
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":"device_flow_disabled"}`)
+	}))
+	defer srv.Close()
+	if _, err := requestDeviceCode(context.Background(), srv.URL+"/", "client"); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("err = %v", err)
+	}
+}
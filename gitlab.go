@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultGitLabURL is used when no GitLab server is configured
+const defaultGitLabURL = "https://gitlab.com"
+
+// gitlabProvider backs up to a private GitLab snippet, creating it on the
+// first backup like the Gist provider does
+type gitlabProvider struct {
+	api *forgeClient
+	id  string
+	// created is called with the ID of a newly created snippet, from the
+	// goroutine saving the backup
+	created func(id string)
+}
+
+// gitlabSnippet is the part of the snippets API response used here
+type gitlabSnippet struct {
+	ID        int       `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// gitlabSnippetFile is a file action of a snippet create or update
+type gitlabSnippetFile struct {
+	Action   string `json:"action,omitempty"`
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
+
+// openGitLabProvider opens the snippet provider once the GitLab token is
+// available
+func openGitLabProvider(am *AliasManager, fn func(BackupProvider)) {
+	am.withServiceToken(serviceGitLab, func(token string) {
+		fn(&gitlabProvider{
+			api: &forgeClient{
+				api:    forgeURL(am.config.GitLabURL, defaultGitLabURL) + "/api/v4",
+				header: "PRIVATE-TOKEN",
+				value:  token,
+			},
+			id: am.config.GitLabSnippetID,
+			created: func(id string) {
+				am.onUI(func() {
+					am.config.GitLabSnippetID = id
+					if err := am.saveConfig(); err != nil {
+						dialog.ShowError(fmt.Errorf("Failed to record the new snippet %s: %v", id, err), am.window)
+					}
+				})
+			},
+		})
+	})
+}
+
+func (g *gitlabProvider) Name() string {
+	return "GitLab snippet"
+}
+
+// explain turns authentication failures into guidance
+func (g *gitlabProvider) explain(err error) error {
+	switch {
+	case isStatus(err, 401):
+		return fmt.Errorf("invalid GitLab token: %v", err)
+	case isStatus(err, 403):
+		return fmt.Errorf("access denied (%v). Ensure your GitLab token has the 'api' scope and snippets are enabled on the server", err)
+	}
+	return err
+}
+
+// Save updates the snippet, or creates a new one when none is configured or
+// the configured one was deleted on the server
+func (g *gitlabProvider) Save(ctx context.Context, content []byte) error {
+	if g.id != "" {
+		body := map[string]interface{}{
+			"files": []gitlabSnippetFile{{Action: "update", FilePath: gistFileName, Content: string(content)}},
+		}
+		err := g.api.call(ctx, "PUT", "/snippets/"+url.PathEscape(g.id), body, nil)
+		if err == nil {
+			return nil
+		}
+		if !isStatus(err, 404) {
+			return fmt.Errorf("updating the snippet failed: %v", g.explain(err))
+		}
+	}
+	body := map[string]interface{}{
+		"title":       "Bash Aliases Backup",
+		"visibility":  "private",
+		"files":       []gitlabSnippetFile{{FilePath: gistFileName, Content: string(content)}},
+		"description": "Backup of the alias file by Bash Alias Manager",
+	}
+	var created gitlabSnippet
+	if err := g.api.call(ctx, "POST", "/snippets", body, &created); err != nil {
+		return fmt.Errorf("creating the snippet failed: %v", g.explain(err))
+	}
+	g.id = strconv.Itoa(created.ID)
+	g.created(g.id)
+	return nil
+}
+
+func (g *gitlabProvider) Load(ctx context.Context) ([]byte, error) {
+	if g.id == "" {
+		return nil, errNoBackup
+	}
+	content, err := g.api.request(ctx, "GET", "/snippets/"+url.PathEscape(g.id)+"/raw", nil)
+	if isStatus(err, 404) {
+		// deleted on the server
+		return nil, errNoBackup
+	}
+	if err != nil {
+		return nil, g.explain(err)
+	}
+	return content, nil
+}
+
+// Revisions returns the latest backup only: snippets are versioned in a
+// repository on the server, but the API does not expose its history
+func (g *gitlabProvider) Revisions(ctx context.Context, limit int) ([]BackupRevision, error) {
+	if g.id == "" {
+		return nil, errNoBackup
+	}
+	var snippet gitlabSnippet
+	if err := g.api.call(ctx, "GET", "/snippets/"+url.PathEscape(g.id), nil, &snippet); err != nil {
+		if isStatus(err, 404) {
+			return nil, errNoBackup
+		}
+		return nil, g.explain(err)
+	}
+	content, err := g.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []BackupRevision{{
+		Version: "latest",
+		Time:    snippet.UpdatedAt.Local().Format("2006-01-02 15:04"),
+		Content: content,
+		Aliases: aliasCount(content),
+	}}, nil
+}
+
+// gitlabSettings configures the GitLab server and snippet. Changing the
+// server forgets the stored token, which belongs to the old one.
+func gitlabSettings(am *AliasManager) fyne.CanvasObject {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder(defaultGitLabURL)
+	urlEntry.SetText(am.config.GitLabURL)
+	idEntry := widget.NewEntry()
+	idEntry.SetPlaceHolder("created on the first backup")
+	idEntry.SetText(am.config.GitLabSnippetID)
+
+	applyBtn := widget.NewButton("Apply", func() {
+		server := strings.TrimSpace(urlEntry.Text)
+		if forgeURL(server, defaultGitLabURL) != forgeURL(am.config.GitLabURL, defaultGitLabURL) {
+			if err := am.forgetToken(serviceGitLab); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to remove the GitLab token: %v", err), am.window)
+			}
+		}
+		am.config.GitLabURL = server
+		am.config.GitLabSnippetID = strings.TrimSpace(idEntry.Text)
+		if err := am.saveConfig(); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+	})
+	form := widget.NewForm(
+		widget.NewFormItem("GitLab server", urlEntry),
+		widget.NewFormItem("Snippet ID", idEntry),
+	)
+	return container.NewVBox(form, container.NewHBox(applyBtn))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitLab serves the snippets API, keeping the latest content of every
+// snippet
+type fakeGitLab struct {
+	mu       sync.Mutex
+	snippets map[string]string
+	next     int
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+		w.WriteHeader(401)
+		fmt.Fprint(w, `{"message":"401 Unauthorized"}`)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rest := strings.TrimPrefix(r.URL.Path, "/api/v4/snippets")
+	id, raw := strings.CutSuffix(strings.TrimPrefix(rest, "/"), "/raw")
+	var body struct {
+		Visibility string              `json:"visibility"`
+		Files      []gitlabSnippetFile `json:"files"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	switch {
+	case r.Method == "POST" && id == "":
+		if body.Visibility != "private" || len(body.Files) != 1 || body.Files[0].FilePath != gistFileName {
+			w.WriteHeader(400)
+			return
+		}
+		f.next++
+		f.snippets[strconv.Itoa(f.next)] = body.Files[0].Content
+		w.WriteHeader(201)
+		json.NewEncoder(w).Encode(gitlabSnippet{ID: f.next})
+		return
+	case id == "":
+		w.WriteHeader(405)
+		return
+	}
+	content, ok := f.snippets[id]
+	if !ok {
+		w.WriteHeader(404)
+		fmt.Fprint(w, `{"message":"404 Snippet Not Found"}`)
+		return
+	}
+	n, _ := strconv.Atoi(id)
+	switch {
+	case r.Method == "PUT":
+		if len(body.Files) != 1 || body.Files[0].Action != "update" {
+			w.WriteHeader(400)
+			return
+		}
+		f.snippets[id] = body.Files[0].Content
+		json.NewEncoder(w).Encode(gitlabSnippet{ID: n})
+	case raw:
+		fmt.Fprint(w, content)
+	default:
+		json.NewEncoder(w).Encode(gitlabSnippet{ID: n, UpdatedAt: time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)})
+	}
+}
+
+func TestGitLabProvider(t *testing.T) {
+	fake := &fakeGitLab{snippets: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	var created []string
+	p := &gitlabProvider{
+		api:     &forgeClient{api: srv.URL + "/api/v4", header: "PRIVATE-TOKEN", value: "secret"},
+		created: func(id string) { created = append(created, id) },
+	}
+	ctx := context.Background()
+	if _, err := p.Load(ctx); !errors.Is(err, errNoBackup) {
+		t.Fatalf("Load before the first backup: %v, want errNoBackup", err)
+	}
+	if _, err := p.Revisions(ctx, 10); !errors.Is(err, errNoBackup) {
+		t.Fatalf("Revisions before the first backup: %v, want errNoBackup", err)
+	}
+
+	for _, b := range []string{"alias a='1'\n", "alias a='1'\nalias b='2'\n"} {
+		if err := p.Save(ctx, []byte(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(created) != 1 || created[0] != "1" || len(fake.snippets) != 1 {
+		t.Fatalf("created %q, snippets %v; want one snippet", created, fake.snippets)
+	}
+	content, err := p.Load(ctx)
+	if err != nil || string(content) != "alias a='1'\nalias b='2'\n" {
+		t.Fatalf("Load = %q, %v; want the latest backup", content, err)
+	}
+	revs, err := p.Revisions(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 1 || revs[0].Version != "latest" || revs[0].Aliases != 2 || string(revs[0].Content) != string(content) {
+		t.Errorf("Revisions = %+v", revs)
+	}
+
+	// a snippet deleted on the server is no backup, and is created again
+	delete(fake.snippets, "1")
+	if _, err := p.Load(ctx); !errors.Is(err, errNoBackup) {
+		t.Errorf("Load of a deleted snippet: %v, want errNoBackup", err)
+	}
+	if err := p.Save(ctx, []byte("alias c='3'\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 || created[1] != "2" || fake.snippets["2"] != "alias c='3'\n" {
+		t.Errorf("created %q, snippets %v; want a new snippet", created, fake.snippets)
+	}
+}
+
+func TestGitLabInvalidToken(t *testing.T) {
+	srv := httptest.NewServer(&fakeGitLab{snippets: map[string]string{}})
+	defer srv.Close()
+	p := &gitlabProvider{api: &forgeClient{api: srv.URL + "/api/v4", header: "PRIVATE-TOKEN", value: "wrong"}, id: "1"}
+	err := p.Save(context.Background(), []byte("alias a='1'\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid GitLab token") || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("Save with a wrong token: %v", err)
+	}
+}
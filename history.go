@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// HistoryEntry is a single command from the shell history file. Time is zero
+// when the history was written without HISTTIMEFORMAT set.
+type HistoryEntry struct {
+	Command string
+	Time    time.Time
+}
+
+// AliasSuggestion is a proposed alias derived from frequently typed commands
+type AliasSuggestion struct {
+	Name    string
+	Command string
+	Count   int
+}
+
+const (
+	// minimum number of times a command must appear before it is suggested
+	suggestMinCount = 3
+	// commands shorter than this are not worth an alias
+	suggestMinLength = 10
+	// maximum number of suggestions shown
+	suggestMaxResults = 20
+)
+
+// parseBashHistory reads bash history lines. When HISTTIMEFORMAT is set bash
+// writes a "#<unix seconds>" comment line before each command, which is
+// attached to the entry that follows it.
+func parseBashHistory(r io.Reader) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	var ts time.Time
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if secs, err := strconv.ParseInt(line[1:], 10, 64); err == nil {
+				ts = time.Unix(secs, 0)
+			}
+			continue
+		}
+		entries = append(entries, HistoryEntry{Command: line, Time: ts})
+		ts = time.Time{}
+	}
+	return entries, scanner.Err()
+}
+
+// parseZshHistory reads zsh history lines. With EXTENDED_HISTORY each line
+// has the form ": <unix seconds>:<duration>;<command>"; plain lines are
+// accepted as well.
+func parseZshHistory(r io.Reader) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var ts time.Time
+		if strings.HasPrefix(line, ": ") {
+			if meta, cmd, ok := strings.Cut(line[2:], ";"); ok {
+				secs, _, _ := strings.Cut(meta, ":")
+				if n, err := strconv.ParseInt(secs, 10, 64); err == nil {
+					ts = time.Unix(n, 0)
+				}
+				line = cmd
+			}
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		entries = append(entries, HistoryEntry{Command: line, Time: ts})
+	}
+	return entries, scanner.Err()
+}
+
+// historyPath returns the history file of the shell, honouring HISTFILE
+func historyPath(shell string) (string, error) {
+	if p := os.Getenv("HISTFILE"); p != "" && shell != shellFish {
+		return p, nil
+	}
+	home, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+	switch shell {
+	case shellZsh:
+		return zshDir(home) + "/.zsh_history", nil
+	case shellFish:
+		return fishHistoryPath(home), nil
+	default:
+		return home + "/.bash_history", nil
+	}
+}
+
+// readShellHistory loads and parses the user's shell history
+func readShellHistory(shell string) ([]HistoryEntry, error) {
+	path, err := historyPath(shell)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []HistoryEntry{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	switch shell {
+	case shellZsh:
+		return parseZshHistory(file)
+	case shellFish:
+		return parseFishHistory(file)
+	default:
+		return parseBashHistory(file)
+	}
+}
+
+// suggestAliases finds frequently typed long commands and command prefixes
+// that are not already covered by an existing alias.
+func suggestAliases(entries []HistoryEntry, existing []Alias) []AliasSuggestion {
+	aliased := map[string]bool{}
+	taken := map[string]bool{}
+	for _, a := range existing {
+		aliased[a.Command] = true
+		taken[a.Name] = true
+	}
+
+	counts := map[string]int{}
+	for _, e := range entries {
+		words := strings.Fields(e.Command)
+		if len(words) == 0 || taken[words[0]] {
+			// already typed through an alias
+			continue
+		}
+		counts[strings.Join(words, " ")]++
+		// count 2 and 3 word prefixes so "git commit -m ..." style commands still surface
+		for n := 2; n <= 3 && n < len(words); n++ {
+			counts[strings.Join(words[:n], " ")]++
+		}
+	}
+
+	var candidates []AliasSuggestion
+	for cmd, n := range counts {
+		if n < suggestMinCount || len(cmd) < suggestMinLength || aliased[cmd] {
+			continue
+		}
+		if !strings.Contains(cmd, " ") {
+			continue
+		}
+		candidates = append(candidates, AliasSuggestion{Command: cmd, Count: n})
+	}
+
+	// rank by keystrokes saved, then alphabetically for a stable order
+	sort.Slice(candidates, func(i, j int) bool {
+		si := candidates[i].Count * len(candidates[i].Command)
+		sj := candidates[j].Count * len(candidates[j].Command)
+		if si != sj {
+			return si > sj
+		}
+		return candidates[i].Command < candidates[j].Command
+	})
+
+	var out []AliasSuggestion
+	for _, c := range candidates {
+		// drop a prefix when a longer command with the same count was already chosen
+		covered := false
+		for _, o := range out {
+			if o.Count == c.Count && strings.HasPrefix(o.Command, c.Command+" ") {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+		c.Name = suggestAliasName(c.Command, taken)
+		taken[c.Name] = true
+		out = append(out, c)
+		if len(out) >= suggestMaxResults {
+			break
+		}
+	}
+	return out
+}
+
+// suggestAliasName builds a short name from the initials of the command's
+// words, e.g. "git status" -> "gs", avoiding existing aliases and commands.
+func suggestAliasName(cmd string, taken map[string]bool) string {
+	var b strings.Builder
+	for _, w := range strings.Fields(cmd) {
+		w = strings.TrimLeft(w, "-")
+		for _, r := range w {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				b.WriteRune(r)
+				break
+			}
+		}
+	}
+	base := strings.ToLower(b.String())
+	if base == "" {
+		base = "a"
+	}
+	name := base
+	for i := 2; ; i++ {
+		if !taken[name] {
+			if _, err := exec.LookPath(name); err != nil {
+				return name
+			}
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// showSuggestions displays aliases suggested from shell history; each can be
+// accepted with one click.
+func (am *AliasManager) showSuggestions() {
+	entries, err := readShellHistory(am.config.targetShell())
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to read shell history: %v", err), am.window)
+		return
+	}
+	suggestions := suggestAliases(entries, am.aliases)
+	if len(suggestions) == 0 {
+		dialog.ShowInformation("Suggestions", "No alias suggestions found in your shell history.", am.window)
+		return
+	}
+
+	var list *widget.List
+	list = widget.NewList(
+		func() int {
+			return len(suggestions)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil, widget.NewButton("Accept", nil), widget.NewLabel("template"))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			s := suggestions[i]
+			row := o.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s = %s  (%d uses)", s.Name, s.Command, s.Count))
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				am.aliases = append(am.aliases, Alias{Name: s.Name, Command: s.Command})
+				am.refreshList()
+				if err := am.saveAliases(); err != nil {
+					dialog.ShowError(err, am.window)
+				}
+				suggestions = append(suggestions[:i], suggestions[i+1:]...)
+				list.Refresh()
+			}
+		},
+	)
+
+	d := dialog.NewCustom("Suggestions", "Close", list, am.window)
+	d.Resize(fyne.NewSize(600, 400))
+	d.Show()
+}
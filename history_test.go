@@ -0,0 +1,156 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHistory(t *testing.T) {
+	at := func(secs int64) time.Time { return time.Unix(secs, 0) }
+	for _, tt := range []struct {
+		name  string
+		parse func(string) ([]HistoryEntry, error)
+		input string
+		want  []HistoryEntry
+	}{
+		{
+			name:  "bash plain",
+			parse: func(s string) ([]HistoryEntry, error) { return parseBashHistory(strings.NewReader(s)) },
+			input: "ls -l\n\n  git status  \n",
+			want:  []HistoryEntry{{Command: "ls -l"}, {Command: "git status"}},
+		},
+		{
+			name:  "bash HISTTIMEFORMAT",
+			parse: func(s string) ([]HistoryEntry, error) { return parseBashHistory(strings.NewReader(s)) },
+			input: "#1700000000\nls -l\ngit status\n#1700000060\nmake\n",
+			want: []HistoryEntry{
+				{Command: "ls -l", Time: at(1700000000)},
+				{Command: "git status"},
+				{Command: "make", Time: at(1700000060)},
+			},
+		},
+		{
+			name:  "bash comment that is not a timestamp",
+			parse: func(s string) ([]HistoryEntry, error) { return parseBashHistory(strings.NewReader(s)) },
+			input: "# notes\nls\n",
+			want:  []HistoryEntry{{Command: "ls"}},
+		},
+		{
+			name:  "zsh extended history",
+			parse: func(s string) ([]HistoryEntry, error) { return parseZshHistory(strings.NewReader(s)) },
+			input: ": 1700000000:0;git status\n: 1700000005:12;make test; echo done\n",
+			want: []HistoryEntry{
+				{Command: "git status", Time: at(1700000000)},
+				{Command: "make test; echo done", Time: at(1700000005)},
+			},
+		},
+		{
+			name:  "zsh plain and empty commands",
+			parse: func(s string) ([]HistoryEntry, error) { return parseZshHistory(strings.NewReader(s)) },
+			input: "ls -l\n: 1700000000:0;\n",
+			want:  []HistoryEntry{{Command: "ls -l"}},
+		},
+		{
+			name:  "fish",
+			parse: func(s string) ([]HistoryEntry, error) { return parseFishHistory(strings.NewReader(s)) },
+			input: "- cmd: git status\n  when: 1700000000\n- cmd: echo a\\nb \\\\ c\n  when: 1700000009\n  paths:\n    - a\n- cmd: ls\n",
+			want: []HistoryEntry{
+				{Command: "git status", Time: at(1700000000)},
+				{Command: "echo a\nb \\ c", Time: at(1700000009)},
+				{Command: "ls"},
+			},
+		},
+	} {
+		got, err := tt.parse(tt.input)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// history returns n entries of each command
+func history(n int, commands ...string) []HistoryEntry {
+	var entries []HistoryEntry
+	for _, c := range commands {
+		for i := 0; i < n; i++ {
+			entries = append(entries, HistoryEntry{Command: c})
+		}
+	}
+	return entries
+}
+
+func TestSuggestAliases(t *testing.T) {
+	// no command on the PATH may clash with a suggested name
+	t.Setenv("PATH", t.TempDir())
+	for _, tt := range []struct {
+		name     string
+		entries  []HistoryEntry
+		existing []Alias
+		want     []AliasSuggestion
+	}{
+		{
+			name:    "below the threshold",
+			entries: history(suggestMinCount-1, "docker compose up -d"),
+		},
+		{
+			name:    "at the threshold",
+			entries: history(suggestMinCount, "docker compose up -d"),
+			want:    []AliasSuggestion{{Name: "dcud", Command: "docker compose up -d", Count: suggestMinCount}},
+		},
+		{
+			name:    "too short",
+			entries: history(10, "ls -l", "cd .."),
+		},
+		{
+			name:    "single words are not suggested",
+			entries: history(10, "./configure-everything"),
+		},
+		{
+			name:     "already aliased command",
+			entries:  history(5, "docker compose up -d"),
+			existing: []Alias{{Name: "up", Command: "docker compose up -d"}},
+			want:     []AliasSuggestion{{Name: "dcu", Command: "docker compose up", Count: 5}},
+		},
+		{
+			name:     "typed through an alias",
+			entries:  history(5, "dc up -d --build"),
+			existing: []Alias{{Name: "dc", Command: "docker compose"}},
+		},
+		{
+			name:     "name taken by an alias",
+			entries:  history(4, "git status --short"),
+			existing: []Alias{{Name: "gss", Command: "git stash save"}},
+			want:     []AliasSuggestion{{Name: "gss2", Command: "git status --short", Count: 4}},
+		},
+		{
+			name:    "shared prefix",
+			entries: history(3, "git commit -m fix", "git commit -m wip", "git commit --amend"),
+			want: []AliasSuggestion{
+				{Name: "gc", Command: "git commit", Count: 9},
+				{Name: "gcm", Command: "git commit -m", Count: 6},
+				{Name: "gca", Command: "git commit --amend", Count: 3},
+				{Name: "gcmf", Command: "git commit -m fix", Count: 3},
+				{Name: "gcmw", Command: "git commit -m wip", Count: 3},
+			},
+		},
+		{
+			name:    "prefixes with the same count are dropped",
+			entries: history(4, "kubectl get pods -A"),
+			want:    []AliasSuggestion{{Name: "kgpa", Command: "kubectl get pods -A", Count: 4}},
+		},
+	} {
+		got := suggestAliases(tt.entries, tt.existing)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Shell integration states reported for each startup file
+const (
+	integrationInstalled = "installed"
+	integrationOutdated  = "outdated"
+	integrationLegacy    = "legacy snippet"
+	integrationDamaged   = "damaged"
+	integrationAbsent    = "not installed"
+)
+
+// IntegrationStatus describes the managed block in one shell startup file.
+// ReadBy explains when bash reads the file and Required is set when the
+// block is needed there for aliases to load in every interactive shell.
+type IntegrationStatus struct {
+	Path     string
+	Exists   bool
+	State    string
+	ReadBy   string
+	Required bool
+}
+
+// integrationFiles returns the startup files of the shell the app knows how to manage
+func integrationFiles(shell, home string) []string {
+	if shell == shellFish {
+		return nil
+	}
+	if shell == shellZsh {
+		return []string{zshDir(home) + "/.zshrc"}
+	}
+	return append([]string{home + "/.bashrc"}, loginFiles(home)...)
+}
+
+// loginFiles returns the files a bash login shell looks for, in order. Bash
+// reads only the first one that exists.
+func loginFiles(home string) []string {
+	return []string{home + "/.bash_profile", home + "/.bash_login", home + "/.profile"}
+}
+
+// activeLoginFile returns the login file bash reads, or the file to create
+// when there is none yet
+func activeLoginFile(home string) string {
+	for _, f := range loginFiles(home) {
+		if _, err := os.Stat(f); err == nil {
+			return f
+		}
+	}
+	return home + "/.bash_profile"
+}
+
+// sourcesBashrc reports whether content loads ~/.bashrc itself, as the
+// default .profile on Debian and .bash_profile on Fedora do
+func sourcesBashrc(content string) bool {
+	if stripped, err := removeManagedBlock(content); err == nil {
+		content = stripped
+	}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") {
+				break
+			}
+			if (f == "." || f == "source") && i+1 < len(fields) && isBashrcPath(fields[i+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isBashrcPath reports whether a sourced word names ~/.bashrc, as in
+// "$HOME/.bashrc", ~/.bashrc; or ${HOME}/.bashrc, but not ~/.bashrc.d/x.sh
+func isBashrcPath(word string) bool {
+	word = strings.TrimRight(word, ";")
+	word = strings.Trim(word, `"'`)
+	return filepath.Base(word) == ".bashrc"
+}
+
+// requiredIntegrationFiles returns the startup files that need the managed
+// block so that both login shells (macOS Terminal, SSH) and non-login
+// interactive shells load the aliases. zsh reads .zshrc for every
+// interactive shell, login or not, and fish needs no startup file at all.
+func requiredIntegrationFiles(shell, home string) []string {
+	if shell == shellFish {
+		return nil
+	}
+	if shell == shellZsh {
+		return []string{zshDir(home) + "/.zshrc"}
+	}
+	files := []string{home + "/.bashrc"}
+	login := activeLoginFile(home)
+	content, _, err := readIntegrationFile(login)
+	if err != nil || !sourcesBashrc(content) {
+		files = append(files, login)
+	}
+	return files
+}
+
+// readByDescription explains when the shell reads path
+func readByDescription(shell, path, home string) string {
+	if shell == shellZsh {
+		return "interactive shells"
+	}
+	if path == home+"/.bashrc" {
+		if runtime.GOOS == "darwin" {
+			return "interactive non-login shells (not Terminal.app)"
+		}
+		return "interactive non-login shells"
+	}
+	login := activeLoginFile(home)
+	if path != login {
+		if _, err := os.Stat(path); err != nil {
+			return "not read"
+		}
+		return "not read (" + filepath.Base(login) + " takes precedence)"
+	}
+	content, _, _ := readIntegrationFile(path)
+	if sourcesBashrc(content) {
+		return "login shells, loads .bashrc"
+	}
+	return "login shells"
+}
+
+// expectedBlock returns the managed block that belongs in the given file.
+// Bash login files may also be read by sh, so the code is guarded to only
+// run in interactive bash.
+func expectedBlock(shell, path, home string) (string, error) {
+	body, err := initScript(shell, home)
+	if err != nil {
+		return "", err
+	}
+	if shell == shellZsh || path == home+"/.bashrc" {
+		return managedBlock(body), nil
+	}
+	var b strings.Builder
+	b.WriteString("if [ -n \"$BASH_VERSION\" ]; then\n    case $- in\n        *i*)\n")
+	for _, line := range splitLines(body) {
+		b.WriteString("            " + line + "\n")
+	}
+	b.WriteString("            ;;\n    esac\nfi\n")
+	return managedBlock(b.String()), nil
+}
+
+// integrationState classifies content against the expected managed block
+func integrationState(content, expected string) string {
+	starts := strings.Count(content, managedBlockStart)
+	ends := strings.Count(content, managedBlockEnd)
+	if starts != ends || starts > 1 {
+		return integrationDamaged
+	}
+	if starts == 1 {
+		start, end, ok := findManagedBlock(content)
+		if !ok {
+			return integrationDamaged
+		}
+		if content[start:end] == expected {
+			return integrationInstalled
+		}
+		return integrationOutdated
+	}
+	for _, s := range legacySnippets {
+		if strings.Contains(content, s) {
+			return integrationLegacy
+		}
+	}
+	return integrationAbsent
+}
+
+// readIntegrationFile returns the content of a startup file, treating a
+// missing file as empty
+func readIntegrationFile(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return string(data), true, nil
+}
+
+// integrationStatus reports the state of the managed block in path
+func integrationStatus(shell, path, home string) (IntegrationStatus, error) {
+	content, exists, err := readIntegrationFile(path)
+	if err != nil {
+		return IntegrationStatus{}, err
+	}
+	expected, err := expectedBlock(shell, path, home)
+	if err != nil {
+		return IntegrationStatus{}, err
+	}
+	required := false
+	for _, f := range requiredIntegrationFiles(shell, home) {
+		if f == path {
+			required = true
+		}
+	}
+	return IntegrationStatus{
+		Path:     path,
+		Exists:   exists,
+		State:    integrationState(content, expected),
+		ReadBy:   readByDescription(shell, path, home),
+		Required: required,
+	}, nil
+}
+
+// planIntegration computes the new content of path for the given action
+// ("install", "repair" or "remove") without writing anything.
+func planIntegration(shell, path, home, action string) (before, after string, err error) {
+	before, _, err = readIntegrationFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	expected, err := expectedBlock(shell, path, home)
+	if err != nil {
+		return "", "", err
+	}
+	state := integrationState(before, expected)
+	switch action {
+	case "install", "repair":
+		if action == "repair" && state == integrationAbsent {
+			return before, before, nil
+		}
+		stripped := before
+		if action == "repair" || state == integrationDamaged {
+			if stripped, err = removeManagedBlock(before); err != nil {
+				return "", "", err
+			}
+		}
+		return before, upsertManagedBlock(stripped, expected), nil
+	case "remove":
+		after, err = removeManagedBlock(before)
+		if err != nil {
+			return "", "", err
+		}
+		return before, after, nil
+	default:
+		return "", "", fmt.Errorf("unknown integration action %q", action)
+	}
+}
+
+// applyIntegration writes the planned content unless nothing changed
+func applyIntegration(path, before, after string) error {
+	if before == after {
+		return nil
+	}
+	return writeFileAtomic(path, []byte(after), 0644)
+}
+
+// runIntegrationCLI implements "integration status|install|repair|remove"
+func runIntegrationCLI(args []string, config Config, home string, stdout, stderr io.Writer) int {
+	shell := config.targetShell()
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage: bash-alias-manager integration status|install|repair|remove [--dry-run] [--shell bash|zsh|fish] [file...]")
+		return 2
+	}
+	action := args[0]
+	dryRun := false
+	var files []string
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		if a == "--dry-run" || a == "-n" {
+			dryRun = true
+			continue
+		}
+		if a == "--shell" && i+1 < len(args) {
+			shell = args[i+1]
+			i++
+			continue
+		}
+		if !filepath.IsAbs(a) {
+			a = filepath.Join(home, a)
+		}
+		files = append(files, a)
+	}
+
+	switch action {
+	case "status":
+		if len(files) == 0 {
+			files = integrationFiles(shell, home)
+		}
+		for _, f := range files {
+			st, err := integrationStatus(shell, f, home)
+			if err != nil {
+				fmt.Fprintf(stderr, "%s: %v\n", f, err)
+				return 1
+			}
+			state := st.State
+			if !st.Exists {
+				state += " (file missing)"
+			}
+			if st.Required && st.State != integrationInstalled {
+				state += ", needed"
+			}
+			fmt.Fprintf(stdout, "%-32s %-40s %s\n", f, state, st.ReadBy)
+		}
+		return 0
+	case "install", "repair", "remove":
+		if action != "remove" && !dryRun {
+			// the block loads other alias files through the loader
+			if err := writeLoader(shell, home, config); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+		}
+		if len(files) == 0 {
+			if action == "install" {
+				files = requiredIntegrationFiles(shell, home)
+			} else {
+				files = integrationFiles(shell, home)
+			}
+		}
+		for _, f := range files {
+			before, after, err := planIntegration(shell, f, home, action)
+			if err != nil {
+				fmt.Fprintf(stderr, "%s: %v\n", f, err)
+				return 1
+			}
+			if before == after {
+				fmt.Fprintf(stdout, "%s: no changes\n", f)
+				continue
+			}
+			fmt.Fprint(stdout, unifiedDiff(before, after, f, f))
+			if dryRun {
+				continue
+			}
+			if err := applyIntegration(f, before, after); err != nil {
+				fmt.Fprintf(stderr, "%s: %v\n", f, err)
+				return 1
+			}
+		}
+		return 0
+	default:
+		fmt.Fprintf(stderr, "Unknown integration action %q\n", action)
+		return 2
+	}
+}
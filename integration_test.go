@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	debianProfile = "# ~/.profile\nif [ -n \"$BASH_VERSION\" ]; then\n    # include .bashrc if it exists\n    if [ -f \"$HOME/.bashrc\" ]; then\n\t. \"$HOME/.bashrc\"\n    fi\nfi\n"
+	fedoraProfile = "# .bash_profile\nif [ -f ~/.bashrc ]; then\n\t. ~/.bashrc\nfi\n"
+)
+
+func TestSourcesBashrc(t *testing.T) {
+	for content, want := range map[string]bool{
+		debianProfile:                               true,
+		fedoraProfile:                               true,
+		"[ -r ~/.bashrc ] && source ~/.bashrc":      true,
+		"source ${HOME}/.bashrc; echo hi":           true,
+		"if [ -f ~/.bashrc ]; then . ~/.bashrc; fi": true,
+		"# . ~/.bashrc":                             false,
+		"export PATH=~/bin:$PATH # . ~/.bashrc":     false,
+		"for f in ~/.bashrc.d/*; do . $f; done":     false,
+		". ~/.bashrc.d/prompt.sh":                   false,
+		"cp ~/.bashrc ~/.bashrc.bak":                false,
+		"":                                          false,
+		managedBlock(". '/h/.bash_aliases'\n"):      false,
+		managedBlock(". ~/.bashrc\n"):               false,
+	} {
+		if got := sourcesBashrc(content); got != want {
+			t.Errorf("sourcesBashrc(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
+
+func TestBashLoginFiles(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		files    map[string]string
+		login    string
+		required []string
+		readBy   map[string]string
+	}{
+		{
+			name:     "no startup files",
+			login:    ".bash_profile",
+			required: []string{".bashrc", ".bash_profile"},
+			readBy:   map[string]string{".bash_profile": "login shells", ".profile": "not read"},
+		},
+		{
+			name:     "Debian .profile loading .bashrc",
+			files:    map[string]string{".bashrc": "", ".profile": debianProfile},
+			login:    ".profile",
+			required: []string{".bashrc"},
+			readBy: map[string]string{
+				".profile":      "login shells, loads .bashrc",
+				".bash_profile": "not read",
+				".bash_login":   "not read",
+			},
+		},
+		{
+			name:     "Fedora .bash_profile loading .bashrc",
+			files:    map[string]string{".bash_profile": fedoraProfile, ".profile": "umask 022\n"},
+			login:    ".bash_profile",
+			required: []string{".bashrc"},
+			readBy: map[string]string{
+				".bash_profile": "login shells, loads .bashrc",
+				".profile":      "not read (.bash_profile takes precedence)",
+			},
+		},
+		{
+			// macOS: a .bash_profile that does not load .bashrc hides the
+			// .profile that does
+			name:     ".bash_profile hiding .profile",
+			files:    map[string]string{".bash_profile": "export PATH=~/bin:$PATH\n", ".profile": debianProfile},
+			login:    ".bash_profile",
+			required: []string{".bashrc", ".bash_profile"},
+			readBy: map[string]string{
+				".bash_profile": "login shells",
+				".profile":      "not read (.bash_profile takes precedence)",
+			},
+		},
+		{
+			name:     ".bash_login before .profile",
+			files:    map[string]string{".bash_login": "echo hi\n", ".profile": debianProfile},
+			login:    ".bash_login",
+			required: []string{".bashrc", ".bash_login"},
+			readBy: map[string]string{
+				".bash_login": "login shells",
+				".profile":    "not read (.bash_login takes precedence)",
+			},
+		},
+		{
+			name:     "loading .bashrc only from a comment",
+			files:    map[string]string{".profile": "# . ~/.bashrc\n"},
+			login:    ".profile",
+			required: []string{".bashrc", ".profile"},
+			readBy:   map[string]string{".profile": "login shells"},
+		},
+	} {
+		home := t.TempDir()
+		for name, content := range tt.files {
+			if err := os.WriteFile(filepath.Join(home, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := activeLoginFile(home); got != filepath.Join(home, tt.login) {
+			t.Errorf("%s: login file %s, want %s", tt.name, got, tt.login)
+		}
+		var required []string
+		for _, f := range requiredIntegrationFiles(shellBash, home) {
+			required = append(required, strings.TrimPrefix(f, home+"/"))
+		}
+		if !reflect.DeepEqual(required, tt.required) {
+			t.Errorf("%s: required %q, want %q", tt.name, required, tt.required)
+		}
+		for name, want := range tt.readBy {
+			if got := readByDescription(shellBash, filepath.Join(home, name), home); got != want {
+				t.Errorf("%s: %s read by %q, want %q", tt.name, name, got, want)
+			}
+		}
+		if got := readByDescription(shellBash, filepath.Join(home, ".bashrc"), home); !strings.HasPrefix(got, "interactive non-login shells") {
+			t.Errorf("%s: .bashrc read by %q", tt.name, got)
+		}
+	}
+}
+
+func TestInstallLoginFileBlock(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	profile := filepath.Join(home, ".bash_profile")
+	user := "export PATH=~/bin:$PATH\n"
+	if err := os.WriteFile(profile, []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before, after, err := planIntegration(shellBash, profile, home, "install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != user || !strings.HasPrefix(after, user) {
+		t.Fatalf("user content changed: %q", after)
+	}
+	// sh reads .profile too, so the block only runs in interactive bash
+	if !strings.Contains(after, "$BASH_VERSION") || !strings.Contains(after, "*i*)") {
+		t.Errorf("login block is not guarded:\n%s", after)
+	}
+	if err := applyIntegration(profile, before, after); err != nil {
+		t.Fatal(err)
+	}
+	st, err := integrationStatus(shellBash, profile, home)
+	if err != nil || st.State != integrationInstalled || !st.Required {
+		t.Errorf("status after install = %+v, %v", st, err)
+	}
+	// the installed block does not count as loading .bashrc
+	if got := requiredIntegrationFiles(shellBash, home); len(got) != 2 {
+		t.Errorf("required after install = %q", got)
+	}
+	if _, after, err := planIntegration(shellBash, profile, home, "remove"); err != nil || after != user {
+		t.Errorf("remove left %q, %v", after, err)
+	}
+}
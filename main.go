@@ -17,9 +17,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-
-	"github.com/google/go-github/v53/github"
-	"golang.org/x/oauth2"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -32,11 +30,65 @@ import (
 type Alias struct {
 	Name    string
 	Command string
+	// Type is empty for a normal alias, or "global"/"suffix" for zsh
+	// `alias -g` and `alias -s` aliases
+	Type string `json:",omitempty"`
+	// Source is the file the alias is defined in; empty means the main alias file
+	Source string `json:"-"`
 }
 
 type Config struct {
-	GitHubToken string `json:"github_token"`
-	GistID      string `json:"gist_id"`
+	// GitHubToken is only read from configs written by older versions; it is
+	// stored in the keyring or the encrypted token file (see TokenStorage)
+	GitHubToken string `json:"github_token,omitempty"`
+	// TokenStorage is where the token is kept: "keyring", "file" or empty
+	TokenStorage string `json:"token_storage,omitempty"`
+	// TokenStores is where the secrets of other services (GitLab, Gitea,
+	// WebDAV, S3) are kept
+	TokenStores map[string]string `json:"token_stores,omitempty"`
+	// GistID is only read from configs written by older versions; it becomes
+	// the "default" entry of GistTargets
+	GistID string `json:"gist_id,omitempty"`
+	// GistTargets are the named Gists backups can go to
+	GistTargets []GistTarget `json:"gist_targets,omitempty"`
+	// GitHubAPIURL and GitHubUploadURL point the Gist client and the update
+	// check at GitHub Enterprise Server or a test server; empty means github.com
+	GitHubAPIURL    string `json:"github_api_url,omitempty"`
+	GitHubUploadURL string `json:"github_upload_url,omitempty"`
+	// GitHubClientID is the OAuth app used to sign in, e.g. one registered on
+	// GitHub Enterprise Server; empty means the built-in OAuthClientID
+	GitHubClientID string `json:"github_client_id,omitempty"`
+	// BackupProvider selects the backup target (see backupTargets); empty means Gist
+	BackupProvider string `json:"backup_provider,omitempty"`
+	// GitRepo, GitFile and GitSync configure the git backup provider
+	GitRepo string `json:"git_repo,omitempty"`
+	GitFile string `json:"git_file,omitempty"`
+	GitSync bool   `json:"git_sync,omitempty"`
+	// GitLabURL and GitLabSnippetID configure the GitLab snippet provider
+	GitLabURL       string `json:"gitlab_url,omitempty"`
+	GitLabSnippetID string `json:"gitlab_snippet_id,omitempty"`
+	// GiteaURL and GiteaRepo configure the Gitea provider
+	GiteaURL  string `json:"gitea_url,omitempty"`
+	GiteaRepo string `json:"gitea_repo,omitempty"`
+	// WebDAVURL and WebDAVUser configure the WebDAV provider
+	WebDAVURL  string `json:"webdav_url,omitempty"`
+	WebDAVUser string `json:"webdav_user,omitempty"`
+	// S3Endpoint to S3AccessKey configure the S3-compatible provider
+	S3Endpoint  string `json:"s3_endpoint,omitempty"`
+	S3Region    string `json:"s3_region,omitempty"`
+	S3Bucket    string `json:"s3_bucket,omitempty"`
+	S3Prefix    string `json:"s3_prefix,omitempty"`
+	S3AccessKey string `json:"s3_access_key,omitempty"`
+	// EncryptBackups encrypts backups with a passphrase before uploading
+	EncryptBackups bool `json:"encrypt_backups,omitempty"`
+	// UsageLogging enables the PROMPT_COMMAND hook that records alias usage
+	UsageLogging bool `json:"usage_logging,omitempty"`
+	// Shell is the target shell ("bash" or "zsh"); empty means bash
+	Shell string `json:"shell,omitempty"`
+	// AliasFile replaces the default main alias file (~/.bash_aliases)
+	AliasFile string `json:"alias_file,omitempty"`
+	// AliasFiles are extra alias files loaded after the main one
+	AliasFiles []string `json:"alias_files,omitempty"`
 }
 
 type AliasManager struct {
@@ -45,56 +97,80 @@ type AliasManager struct {
 	window        fyne.Window
 	selectedIndex int
 	config        Config
+	usage         map[string]AliasUsage
+	// backupPassphrase is remembered for the session once entered
+	backupPassphrase string
+	// plainToken is set while the token read from the config file has not
+	// been moved to secure storage yet
+	plainToken bool
+	// tokens holds the secrets of services other than GitHub once loaded
+	// this session
+	tokens map[string]string
+	// pending is a backup waiting to be sent again (see queueBackup),
+	// pendingRetrying is set while retryPendingBackup runs and pendingCancel
+	// cancels its running attempt; all are guarded by pendingMu
+	pending         *pendingBackup
+	pendingRetrying bool
+	pendingCancel   context.CancelFunc
+	pendingMu       sync.Mutex
+	// queue runs a function on the window's event goroutine (see onUI)
+	queue func(func())
 }
 
 // Version is set at build time via -ldflags "-X main.Version=..."
 var Version = "dev"
 
+// releaseRepo is the repository whose releases the update check looks at
+const releaseRepo = "ahrasel/go-bash-alias-manager"
+
+// releaseURL returns the latest release of releaseRepo on the configured
+// GitHub API, github.com unless set in Settings
+func (c Config) releaseURL() string {
+	api, _ := c.githubURLs()
+	return api + "repos/" + releaseRepo + "/releases/latest"
+}
+
 //go:embed assets/icon.svg
 var iconSVG []byte
 
 func (am *AliasManager) loadAliases() error {
-	home := os.Getenv("SNAP_REAL_HOME")
-	if home == "" {
-		var err error
-		home, err = os.UserHomeDir()
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	if am.config.targetShell() == shellFish {
+		aliases, err := loadFishAliases(home)
 		if err != nil {
 			return err
 		}
+		am.aliases = aliases
+		debugf("Total fish aliases loaded: %d\n", len(am.aliases))
+		return nil
 	}
-	fmt.Fprintf(os.Stderr, "Loading aliases from: %s/.bash_aliases\n", home)
-	file, err := os.Open(home + "/.bash_aliases")
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "File does not exist, creating empty alias list\n")
-			am.aliases = []Alias{}
-			return nil
-		}
-		// Permission denied indicates confinement (snap) preventing dotfile access
-		if os.IsPermission(err) {
-			return fmt.Errorf("permission-denied")
-		}
-		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
-		return err
-	}
-	defer file.Close()
-
+	shell := am.config.targetShell()
 	am.aliases = []Alias{}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "alias ") && strings.Contains(line, "=") {
-			parts := strings.SplitN(line[6:], "=", 2)
-			if len(parts) == 2 {
-				name := strings.TrimSpace(parts[0])
-				cmd := strings.Trim(strings.TrimSpace(parts[1]), "'\"")
-				am.aliases = append(am.aliases, Alias{Name: name, Command: cmd})
-				fmt.Fprintf(os.Stderr, "Loaded alias: %s = %s\n", name, cmd)
+	for i, path := range aliasSources(shell, home, am.aliasFile(home), am.config.AliasFiles) {
+		debugf("Loading aliases from: %s\n", path)
+		aliases, err := readAliasFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				debugf("File does not exist, skipping\n")
+				continue
+			}
+			// Permission denied on the main file indicates confinement (snap) preventing dotfile access
+			if os.IsPermission(err) && i == 0 {
+				return fmt.Errorf("permission-denied")
 			}
+			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+			return err
 		}
+		for _, alias := range aliases {
+			debugf("Loaded alias: %s = %s\n", alias.Name, alias.Command)
+		}
+		am.aliases = append(am.aliases, aliases...)
 	}
-	fmt.Fprintf(os.Stderr, "Total aliases loaded: %d\n", len(am.aliases))
-	return scanner.Err()
+	debugf("Total aliases loaded: %d\n", len(am.aliases))
+	return nil
 }
 
 // importAliasesFromBytes loads aliases from the provided bytes
@@ -102,19 +178,109 @@ func (am *AliasManager) importAliasesFromBytes(content []byte) error {
 	am.aliases = []Alias{}
 	scanner := bufio.NewScanner(strings.NewReader(string(content)))
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "alias ") && strings.Contains(line, "=") {
-			parts := strings.SplitN(line[6:], "=", 2)
-			if len(parts) == 2 {
-				name := strings.TrimSpace(parts[0])
-				cmd := strings.Trim(strings.TrimSpace(parts[1]), "'\"")
-				am.aliases = append(am.aliases, Alias{Name: name, Command: cmd})
-			}
+		if alias, ok := parseAliasLine(scanner.Text()); ok {
+			am.aliases = append(am.aliases, alias)
 		}
 	}
 	return scanner.Err()
 }
 
+// parseAliasLine parses an `alias name='command'` line, including the zsh
+// `alias -g` (global) and `alias -s` (suffix) forms and fish `abbr` lines
+func parseAliasLine(line string) (Alias, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "abbr ") {
+		return parseFishAbbr(line)
+	}
+	if !strings.HasPrefix(line, "alias ") || !strings.Contains(line, "=") {
+		return Alias{}, false
+	}
+	rest := strings.TrimSpace(line[6:])
+	aliasType := ""
+	for strings.HasPrefix(rest, "-") {
+		opt, tail, _ := strings.Cut(rest, " ")
+		switch opt {
+		case "-g":
+			aliasType = "global"
+		case "-s":
+			aliasType = "suffix"
+		}
+		rest = strings.TrimSpace(tail)
+		if opt == "--" {
+			break
+		}
+	}
+	parts := strings.SplitN(rest, "=", 2)
+	if len(parts) != 2 {
+		return Alias{}, false
+	}
+	name := strings.TrimSpace(parts[0])
+	cmd := shellUnquote(strings.TrimSpace(parts[1]))
+	return Alias{Name: name, Command: cmd, Type: aliasType}, true
+}
+
+// shellQuote quotes s for the shell in single quotes. A single quote inside
+// closes the quoting, is escaped with a backslash and reopens it.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellUnquote removes the single quotes, double quotes and backslash
+// escapes of an alias value. Unquoted text is kept as it is, including spaces.
+func shellUnquote(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				b.WriteString(s[i+1:])
+				return b.String()
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case '"':
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\", s[i+1]) >= 0 {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// renderAliases renders aliases in alias file syntax
+func renderAliases(aliases []Alias) string {
+	var b strings.Builder
+	for _, alias := range aliases {
+		b.WriteString(formatAlias(alias) + "\n")
+	}
+	return b.String()
+}
+
+// formatAlias renders an alias as a line for the alias file
+func formatAlias(alias Alias) string {
+	switch alias.Type {
+	case "global":
+		return fmt.Sprintf("alias -g %s=%s", alias.Name, shellQuote(alias.Command))
+	case "suffix":
+		return fmt.Sprintf("alias -s %s=%s", alias.Name, shellQuote(alias.Command))
+	case "abbr":
+		return fmt.Sprintf("abbr -a -- %s %s", alias.Name, fishQuote(alias.Command))
+	default:
+		return fmt.Sprintf("alias %s=%s", alias.Name, shellQuote(alias.Command))
+	}
+}
+
 // promptForAliasFile opens a file dialog to let user select an aliases file for import
 func (am *AliasManager) promptForAliasFile() {
 	fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
@@ -138,70 +304,60 @@ func (am *AliasManager) promptForAliasFile() {
 }
 
 func (am *AliasManager) saveAliases() error {
-	home := os.Getenv("SNAP_REAL_HOME")
-	if home == "" {
-		var err error
-		home, err = os.UserHomeDir()
-		if err != nil {
-			return err
-		}
-	}
-	file, err := os.Create(home + "/.bash_aliases")
+	home, err := homeDir()
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	for _, alias := range am.aliases {
-		fmt.Fprintf(file, "alias %s='%s'\n", alias.Name, alias.Command)
+	if am.config.targetShell() == shellFish {
+		return saveFishAliases(home, am.aliases)
 	}
-	return nil
+	shell := am.config.targetShell()
+	sources := aliasSources(shell, home, am.aliasFile(home), am.config.AliasFiles)
+	if err := saveAliasSources(sources, am.aliases); err != nil {
+		return err
+	}
+	return writeLoader(shell, home, am.config)
 }
 
-func (am *AliasManager) ensureBashrcSources() error {
-	home := os.Getenv("SNAP_REAL_HOME")
-	if home == "" {
-		var err error
-		home, err = os.UserHomeDir()
-		if err != nil {
-			return err
-		}
-	}
-	bashrcPath := home + "/.bashrc"
-	file, err := os.Open(bashrcPath)
+// ensureShellIntegration installs or refreshes the managed block in every
+// startup file the target shell reads for interactive shells: .zshrc for zsh;
+// for bash .bashrc, plus the login file when it does not load .bashrc itself.
+// Running it repeatedly leaves the files unchanged.
+func (am *AliasManager) ensureShellIntegration() error {
+	home, err := homeDir()
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if strings.Contains(scanner.Text(), ".bash_aliases") {
-			return nil // already sourced
+	shell := am.config.targetShell()
+	// the loader picks up drop-in files added outside the app
+	if err := writeLoader(shell, home, am.config); err != nil {
+		return err
+	}
+	for _, path := range requiredIntegrationFiles(shell, home) {
+		before, after, err := planIntegration(shell, path, home, "install")
+		if err != nil {
+			return err
+		}
+		if err := applyIntegration(path, before, after); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	// append to .bashrc
-	f, err := os.OpenFile(bashrcPath, os.O_APPEND|os.O_WRONLY, 0644)
+func (am *AliasManager) loadConfig() error {
+	home, err := homeDir()
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	_, err = f.WriteString("\n# Source bash aliases\nif [ -f ~/.bash_aliases ]; then\n    . ~/.bash_aliases\nfi\n")
-	return err
-}
-
-func (am *AliasManager) loadConfig() error {
-	home := os.Getenv("SNAP_REAL_HOME")
-	if home == "" {
-		var err error
-		home, err = os.UserHomeDir()
-		if err != nil {
-			return err
-		}
+	if err := migrateLegacyFiles(home); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+	file, err := os.Open(configPath(home))
+	if os.IsNotExist(err) && configFileOverride == "" {
+		// migration failed; keep using the legacy file rather than losing the Gist ID and token
+		file, err = os.Open(filepath.Join(home, legacyConfigFile))
 	}
-	configPath := home + "/.bash_alias_manager.json"
-	file, err := os.Open(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			am.config = Config{}
@@ -210,25 +366,37 @@ func (am *AliasManager) loadConfig() error {
 		return err
 	}
 	defer file.Close()
-	return json.NewDecoder(file).Decode(&am.config)
+	if err := json.NewDecoder(file).Decode(&am.config); err != nil {
+		return err
+	}
+	am.plainToken = am.config.GitHubToken != ""
+	am.config.migrateGistID()
+	return nil
 }
 
 func (am *AliasManager) saveConfig() error {
-	home := os.Getenv("SNAP_REAL_HOME")
-	if home == "" {
-		var err error
-		home, err = os.UserHomeDir()
-		if err != nil {
-			return err
-		}
+	home, err := homeDir()
+	if err != nil {
+		return err
 	}
-	configPath := home + "/.bash_alias_manager.json"
-	file, err := os.Create(configPath)
+	path := configPath(home)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	return json.NewEncoder(file).Encode(am.config)
+	if err := file.Chmod(0600); err != nil {
+		return err
+	}
+	cfg := am.config
+	if !am.plainToken {
+		// the token lives in the keyring or the token file
+		cfg.GitHubToken = ""
+	}
+	return json.NewEncoder(file).Encode(cfg)
 }
 
 func (am *AliasManager) refreshList() {
@@ -252,143 +420,211 @@ func (am *AliasManager) showAbout() {
 
 // checkForUpdate contacts GitHub Releases, checks latest tag and if newer offers to download and replace the binary.
 func (am *AliasManager) checkForUpdate() {
-	url := "https://api.github.com/repos/ahrasel/go-bash-alias-manager/releases/latest"
-	resp, err := http.Get(url)
+	url := am.config.releaseURL()
+	var rel map[string]interface{}
+	am.runInBackground("Update", "Checking for a new version…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+		var err error
+		rel, err = fetchRelease(ctx, url)
+		return err
+	}, func(err error) {
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to check for update: %v", err), am.window)
+			return
+		}
+		tag, _ := rel["tag_name"].(string)
+		if tag == "" {
+			dialog.ShowInformation("Update", "No release tag found", am.window)
+			return
+		}
+		if !versionGreater(tag, Version) {
+			dialog.ShowInformation("Update", "You are already running the latest version", am.window)
+			return
+		}
+		confirm := dialog.NewConfirm("Update available", fmt.Sprintf("A new version %s is available (current %s). Update now?", tag, Version), func(ok bool) {
+			if ok {
+				am.installUpdate(tag, rel)
+			}
+		}, am.window)
+		confirm.Show()
+	})
+}
+
+// fetchRelease downloads the release description at url
+func fetchRelease(ctx context.Context, url string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("Failed to check for update: %v", err), am.window)
-		return
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		dialog.ShowError(fmt.Errorf("Update check failed: status %d", resp.StatusCode), am.window)
-		return
+		return nil, statusError(resp)
 	}
 	var rel map[string]interface{}
-	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(&rel); err != nil {
-		dialog.ShowError(fmt.Errorf("Invalid response checking updates: %v", err), am.window)
-		return
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	return rel, nil
+}
+
+// installUpdate downloads the release asset for this platform in the
+// background and replaces the running binary with it
+func (am *AliasManager) installUpdate(tag string, rel map[string]interface{}) {
+	// find matching asset
+	assets, _ := rel["assets"].([]interface{})
+	goos := runtime.GOOS
+	goarch := runtime.GOARCH
+	// map GOARCH names to our asset naming (amd64 -> amd64, arm64 -> arm64)
+	var assetURL string
+	var assetName string
+	for _, a := range assets {
+		m := a.(map[string]interface{})
+		name, _ := m["name"].(string)
+		if strings.Contains(name, fmt.Sprintf("_%s_%s", goos, goarch)) {
+			if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".zip") {
+				assetURL = m["browser_download_url"].(string)
+				assetName = name
+				break
+			}
+		}
 	}
-	tag, _ := rel["tag_name"].(string)
-	if tag == "" {
-		dialog.ShowInformation("Update", "No release tag found", am.window)
+	if assetURL == "" {
+		dialog.ShowError(fmt.Errorf("No matching release asset found for %s/%s", goos, goarch), am.window)
 		return
 	}
-	if !versionGreater(tag, Version) {
-		dialog.ShowInformation("Update", "You are already running the latest version", am.window)
+	if strings.HasSuffix(assetName, ".zip") && exec.Command("unzip", "-v").Run() != nil {
+		dialog.ShowInformation("Update", "Zip-based assets are not supported for automatic updates on this system; please re-run the installer from the release page.", am.window)
 		return
 	}
-	confirm := dialog.NewConfirm("Update available", fmt.Sprintf("A new version %s is available (current %s). Update now?", tag, Version), func(ok bool) {
-		if !ok {
-			return
-		}
-		// find matching asset
-		assets, _ := rel["assets"].([]interface{})
-		goos := runtime.GOOS
-		goarch := runtime.GOARCH
-		// map GOARCH names to our asset naming (amd64 -> amd64, arm64 -> arm64)
-		var assetURL string
-		var assetName string
-		for _, a := range assets {
-			m := a.(map[string]interface{})
-			name, _ := m["name"].(string)
-			if strings.Contains(name, fmt.Sprintf("_%s_%s", goos, goarch)) {
-				if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".zip") {
-					assetURL = m["browser_download_url"].(string)
-					assetName = name
-					break
-				}
-			}
-		}
-		if assetURL == "" {
-			dialog.ShowError(fmt.Errorf("No matching release asset found for %s/%s", goos, goarch), am.window)
-			return
-		}
-		// Download asset
-		tmpd, err := os.MkdirTemp("", "bam-update-")
-		if err != nil {
-			dialog.ShowError(err, am.window)
-			return
-		}
-		defer os.RemoveAll(tmpd)
-		// preserve original filename extension to help extraction
-		assetPath := filepath.Join(tmpd, assetName)
-		out, err := os.Create(assetPath)
-		if err != nil {
-			dialog.ShowError(err, am.window)
-			return
-		}
-		resp2, err := http.Get(assetURL)
+	var sum [sha256.Size]byte
+	am.runInBackground("Update", "Downloading "+assetName+"…", updateTimeout, func(ctx context.Context, status func(string)) error {
+		var err error
+		sum, err = downloadUpdate(ctx, assetURL, assetName, status)
+		return err
+	}, func(err error) {
 		if err != nil {
 			dialog.ShowError(err, am.window)
-			out.Close()
 			return
 		}
-		_, err = io.Copy(out, resp2.Body)
+		dialog.ShowInformation("Update", fmt.Sprintf("Updated to %s (sha256: %s). Please restart the application.", tag, hex.EncodeToString(sum[:])), am.window)
+	})
+}
+
+// downloadUpdate downloads and extracts the release asset and overwrites the
+// running executable with the binary inside. It returns the binary's SHA-256.
+func downloadUpdate(ctx context.Context, assetURL, assetName string, status func(string)) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	home, _ := homeDir()
+	if err := os.MkdirAll(cacheDir(home), 0700); err != nil {
+		return sum, err
+	}
+	tmpd, err := os.MkdirTemp(cacheDir(home), "update-")
+	if err != nil {
+		return sum, err
+	}
+	defer os.RemoveAll(tmpd)
+	// preserve original filename extension to help extraction
+	assetPath := filepath.Join(tmpd, assetName)
+	out, err := os.Create(assetPath)
+	if err != nil {
+		return sum, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", assetURL, nil)
+	if err != nil {
+		out.Close()
+		return sum, err
+	}
+	resp2, err := httpClient.Do(req)
+	if err != nil {
+		out.Close()
+		return sum, err
+	}
+	if resp2.StatusCode != 200 {
 		resp2.Body.Close()
 		out.Close()
-		if err != nil {
-			dialog.ShowError(err, am.window)
-			return
+		return sum, fmt.Errorf("Failed to download update: status %d", resp2.StatusCode)
+	}
+	_, err = io.Copy(out, &downloadProgress{r: resp2.Body, total: resp2.ContentLength, name: assetName, status: status})
+	resp2.Body.Close()
+	out.Close()
+	if err != nil {
+		return sum, err
+	}
+	status("Installing " + assetName + "…")
+	// Extract and locate binary
+	extractDir := filepath.Join(tmpd, "extracted")
+	os.MkdirAll(extractDir, 0755)
+	if strings.HasSuffix(assetName, ".zip") {
+		if err := exec.CommandContext(ctx, "unzip", "-q", assetPath, "-d", extractDir).Run(); err != nil {
+			return sum, fmt.Errorf("Failed to unzip update: %v", err)
 		}
-		// Extract and locate binary
-		extractDir := filepath.Join(tmpd, "extracted")
-		os.MkdirAll(extractDir, 0755)
-		if strings.HasSuffix(assetName, ".zip") {
-			// try unzip if available
-			if exec.Command("unzip", "-v").Run() == nil {
-				if err := exec.Command("unzip", "-q", assetPath, "-d", extractDir).Run(); err != nil {
-					dialog.ShowError(fmt.Errorf("Failed to unzip update: %v", err), am.window)
-					return
-				}
-			} else {
-				dialog.ShowInformation("Update", "Zip-based assets are not supported for automatic updates on this system; please re-run the installer from the release page.", am.window)
-				return
-			}
-		} else {
-			// try tar.gz
-			if err := exec.Command("tar", "-xzf", assetPath, "-C", extractDir).Run(); err != nil {
-				dialog.ShowError(fmt.Errorf("Failed to extract update: %v", err), am.window)
-				return
-			}
+	} else {
+		// try tar.gz
+		if err := exec.CommandContext(ctx, "tar", "-xzf", assetPath, "-C", extractDir).Run(); err != nil {
+			return sum, fmt.Errorf("Failed to extract update: %v", err)
 		}
-		// find binary
-		var newBin string
-		filepath.WalkDir(extractDir, func(p string, d os.DirEntry, err error) error {
-			if err != nil {
-				return nil
-			}
-			if !d.IsDir() {
-				// Accept file named bash-alias-manager (or with .exe on windows) irrespective of exec bit
-				if d.Name() == "bash-alias-manager" || d.Name() == "bash-alias-manager.exe" || strings.Contains(d.Name(), "bash-alias-manager_") || strings.HasSuffix(d.Name(), "bash-alias-manager") {
-					newBin = p
-					return io.EOF
-				}
-			}
+	}
+	// find binary
+	var newBin string
+	filepath.WalkDir(extractDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
 			return nil
-		})
-		if newBin == "" {
-			dialog.ShowError(fmt.Errorf("Could not find new binary in archive"), am.window)
-			return
 		}
-		// compute checksum of downloaded file for info
-		data, _ := os.ReadFile(newBin)
-		sum := sha256.Sum256(data)
-		// attempt to replace current executable
-		exe, err := os.Executable()
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("Could not determine executable path: %v", err), am.window)
-			return
+		if !d.IsDir() {
+			// Accept file named bash-alias-manager (or with .exe on windows) irrespective of exec bit
+			if d.Name() == "bash-alias-manager" || d.Name() == "bash-alias-manager.exe" || strings.Contains(d.Name(), "bash-alias-manager_") || strings.HasSuffix(d.Name(), "bash-alias-manager") {
+				newBin = p
+				return io.EOF
+			}
 		}
-		// try to overwrite
-		if err := os.WriteFile(exe, data, 0755); err != nil {
-			// likely permission denied
-			dialog.ShowError(fmt.Errorf("Failed to update in-place: %v. Please run the installer as described in the README.", err), am.window)
-			return
+		return nil
+	})
+	if newBin == "" {
+		return sum, fmt.Errorf("Could not find new binary in archive")
+	}
+	// compute checksum of downloaded file for info
+	data, _ := os.ReadFile(newBin)
+	sum = sha256.Sum256(data)
+	// attempt to replace current executable
+	exe, err := os.Executable()
+	if err != nil {
+		return sum, fmt.Errorf("Could not determine executable path: %v", err)
+	}
+	if err := ctx.Err(); err != nil {
+		// cancelled: leave the installed version alone
+		return sum, err
+	}
+	// try to overwrite
+	if err := os.WriteFile(exe, data, 0755); err != nil {
+		// likely permission denied
+		return sum, fmt.Errorf("Failed to update in-place: %v. Please run the installer as described in the README.", err)
+	}
+	return sum, nil
+}
+
+// downloadProgress reports the share of a download read so far
+type downloadProgress struct {
+	r       io.Reader
+	total   int64
+	read    int64
+	percent int64
+	name    string
+	status  func(string)
+}
+
+func (d *downloadProgress) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.read += int64(n)
+	if d.total > 0 {
+		if percent := d.read * 100 / d.total; percent != d.percent {
+			d.percent = percent
+			d.status(fmt.Sprintf("Downloading %s… %d%%", d.name, percent))
 		}
-		dialog.ShowInformation("Update", fmt.Sprintf("Updated to %s (sha256: %s). Please restart the application.", tag, hex.EncodeToString(sum[:])), am.window)
-	}, am.window)
-	confirm.Show()
+	}
+	return n, err
 }
 
 // versionGreater compares semantic versions like v1.2.3
@@ -421,195 +657,13 @@ func versionGreater(a, b string) bool {
 	return false
 }
 
-func (am *AliasManager) backupToGist() {
-	if am.config.GitHubToken == "" {
-		tokenEntry := widget.NewPasswordEntry()
-		tokenEntry.SetPlaceHolder("GitHub Personal Access Token")
-
-		var d *dialog.CustomDialog
-		form := &widget.Form{
-			Items: []*widget.FormItem{
-				{Text: "Token:", Widget: tokenEntry},
-			},
-			OnSubmit: func() {
-				am.config.GitHubToken = tokenEntry.Text
-				d.Hide()
-				am.doBackup()
-			},
-		}
-		d = dialog.NewCustom("Enter GitHub Token", "Cancel", form, am.window)
-		d.Resize(fyne.NewSize(400, 100))
-		d.Show()
-		return
-	}
-	am.doBackup()
-}
-
-func (am *AliasManager) doBackup() {
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: am.config.GitHubToken})
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-
-	// Validate token
-	_, _, err := client.Users.Get(ctx, "")
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("Invalid GitHub token: %v", err), am.window)
-		return
-	}
-
-	home := os.Getenv("SNAP_REAL_HOME")
-	if home == "" {
-		home, _ = os.UserHomeDir()
-	}
-	content, err := os.ReadFile(home + "/.bash_aliases")
-	if err != nil {
-		if os.IsNotExist(err) {
-			content = []byte("")
-			// proceed with empty content
-		} else if os.IsPermission(err) {
-			// Cannot read dotfile due to confinement: ask user to select file to backup
-			fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, rerr error) {
-				if rerr != nil || reader == nil {
-					return
-				}
-				defer reader.Close()
-				b, _ := ioutil.ReadAll(reader)
-				am.createGistFromContent(b)
-			}, am.window)
-			fd.SetFilter(storage.NewExtensionFileFilter([]string{"aliases", "txt", "sh"}))
-			fd.Show()
-			return
-		} else {
-			dialog.ShowError(err, am.window)
-			return
-		}
-	}
-	am.createGistFromContent(content)
-}
-
-func (am *AliasManager) createGistFromContent(content []byte) {
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: am.config.GitHubToken})
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-
-	files := map[github.GistFilename]github.GistFile{
-		"bash_aliases": {Content: github.String(string(content))},
-	}
-
-	gist := &github.Gist{
-		Description: github.String("Bash Aliases Backup"),
-		Public:      github.Bool(false),
-		Files:       files,
-	}
-
-	if am.config.GistID == "" {
-		// Create new
-		g, resp, err := client.Gists.Create(ctx, gist)
-		if err != nil {
-			// Provide clearer guidance for common permission errors (403/404) which often mean missing 'gist' scope
-			if resp != nil && (resp.StatusCode == 403 || resp.StatusCode == 404) {
-				dialog.ShowError(fmt.Errorf("Failed to create Gist (status %d). Ensure your GitHub token has the 'gist' scope and is valid. Error: %v", resp.StatusCode, err), am.window)
-				return
-			}
-			dialog.ShowError(fmt.Errorf("Failed to create Gist: %v", err), am.window)
-			return
-		}
-		am.config.GistID = *g.ID
-		err = am.saveConfig()
-		if err != nil {
-			dialog.ShowError(err, am.window)
-		}
-	} else {
-		// Update
-		_, _, err := client.Gists.Edit(ctx, am.config.GistID, gist)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("Failed to update Gist: %v", err), am.window)
-			return
-		}
-	}
-
-	dialog.ShowInformation("Backup", "Aliases backed up to GitHub Gist successfully!", am.window)
-}
-
-func (am *AliasManager) restoreFromGist() {
-	if am.config.GitHubToken == "" || am.config.GistID == "" {
-		dialog.ShowInformation("Restore", "No backup found. Please backup first.", am.window)
-		return
-	}
-
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: am.config.GitHubToken})
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-
-	// Validate token
-	_, _, err := client.Users.Get(ctx, "")
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("Invalid GitHub token: %v", err), am.window)
-		return
-	}
-
-	gist, _, err := client.Gists.Get(ctx, am.config.GistID)
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("Failed to get Gist: %v", err), am.window)
-		return
-	}
-
-	file, ok := gist.Files["bash_aliases"]
-	if !ok {
-		dialog.ShowError(fmt.Errorf("bash_aliases file not found in gist"), am.window)
-		return
-	}
-
-	home := os.Getenv("SNAP_REAL_HOME")
-	if home == "" {
-		home, _ = os.UserHomeDir()
-	}
-	err = os.WriteFile(home+"/.bash_aliases", []byte(*file.Content), 0644)
-	if err != nil {
-		if os.IsPermission(err) {
-			// Ask user to save file via portal
-			fd := dialog.NewFileSave(func(writer fyne.URIWriteCloser, werr error) {
-				if werr != nil || writer == nil {
-					return
-				}
-				defer writer.Close()
-				if _, werr := writer.Write([]byte(*file.Content)); werr != nil {
-					dialog.ShowError(werr, am.window)
-					return
-				}
-				// After saving, load the content into the app
-				if lerr := am.importAliasesFromBytes([]byte(*file.Content)); lerr != nil {
-					dialog.ShowError(lerr, am.window)
-					return
-				}
-				am.refreshList()
-			}, am.window)
-			fd.SetFileName(".bash_aliases")
-			fd.SetFilter(storage.NewExtensionFileFilter([]string{"aliases", "txt", "sh"}))
-			fd.Show()
-			return
-		}
-		dialog.ShowError(err, am.window)
-		return
-	}
-
-	err = am.loadAliases()
-	if err != nil {
-		dialog.ShowError(err, am.window)
-		return
-	}
-	am.refreshList()
-	dialog.ShowInformation("Restore", "Aliases restored from GitHub Gist successfully!", am.window)
-}
-
 func (am *AliasManager) addAlias() {
 	nameEntry := widget.NewEntry()
 	nameEntry.SetPlaceHolder("Alias name")
 	cmdEntry := widget.NewEntry()
 	cmdEntry.SetPlaceHolder("Command")
+	typeSelect := aliasTypeSelect(am.config.targetShell(), "")
+	fileSelect, selectedFile := am.sourceSelect("")
 
 	var d *dialog.CustomDialog
 	form := &widget.Form{
@@ -621,7 +675,7 @@ func (am *AliasManager) addAlias() {
 			if nameEntry.Text == "" || cmdEntry.Text == "" {
 				return
 			}
-			am.aliases = append(am.aliases, Alias{Name: nameEntry.Text, Command: cmdEntry.Text})
+			am.aliases = append(am.aliases, Alias{Name: nameEntry.Text, Command: cmdEntry.Text, Type: selectedAliasType(typeSelect), Source: selectedFile()})
 			am.refreshList()
 			err := am.saveAliases()
 			if err != nil {
@@ -631,6 +685,13 @@ func (am *AliasManager) addAlias() {
 		},
 	}
 
+	if am.config.targetShell() != shellBash {
+		form.Append("Type:", typeSelect)
+	}
+	if fileSelect != nil {
+		form.Append("File:", fileSelect)
+	}
+
 	d = dialog.NewCustom("Add Alias", "Cancel", form, am.window)
 	d.Resize(fyne.NewSize(400, 200))
 	d.Show()
@@ -646,6 +707,9 @@ func (am *AliasManager) editAlias(index int) {
 	nameEntry.SetText(alias.Name)
 	cmdEntry := widget.NewEntry()
 	cmdEntry.SetText(alias.Command)
+	typeSelect := aliasTypeSelect(am.config.targetShell(), alias.Type)
+	// changing the file moves the alias there
+	fileSelect, selectedFile := am.sourceSelect(alias.Source)
 
 	var d *dialog.CustomDialog
 	form := &widget.Form{
@@ -657,7 +721,7 @@ func (am *AliasManager) editAlias(index int) {
 			if nameEntry.Text == "" || cmdEntry.Text == "" {
 				return
 			}
-			am.aliases[index] = Alias{Name: nameEntry.Text, Command: cmdEntry.Text}
+			am.aliases[index] = Alias{Name: nameEntry.Text, Command: cmdEntry.Text, Type: selectedAliasType(typeSelect), Source: selectedFile()}
 			am.refreshList()
 			err := am.saveAliases()
 			if err != nil {
@@ -667,11 +731,41 @@ func (am *AliasManager) editAlias(index int) {
 		},
 	}
 
+	if am.config.targetShell() != shellBash || alias.Type != "" {
+		form.Append("Type:", typeSelect)
+	}
+	if fileSelect != nil {
+		form.Append("File:", fileSelect)
+	}
+
 	d = dialog.NewCustom("Edit Alias", "Cancel", form, am.window)
 	d.Resize(fyne.NewSize(400, 200))
 	d.Show()
 }
 
+// aliasTypeSelect returns a selector for the alias types of the shell: zsh
+// global and suffix aliases, or fish abbreviations
+func aliasTypeSelect(shell, current string) *widget.Select {
+	options := []string{"normal", "global", "suffix"}
+	if shell == shellFish {
+		options = []string{"normal", "abbr"}
+	}
+	sel := widget.NewSelect(options, nil)
+	if current == "" {
+		current = "normal"
+	}
+	sel.SetSelected(current)
+	return sel
+}
+
+// selectedAliasType returns the Alias.Type value chosen in a type selector
+func selectedAliasType(sel *widget.Select) string {
+	if sel.Selected == "normal" {
+		return ""
+	}
+	return sel.Selected
+}
+
 func (am *AliasManager) deleteAlias(index int) {
 	if index < 0 || index >= len(am.aliases) {
 		return
@@ -695,12 +789,24 @@ func (am *AliasManager) reloadAliases() {
 		dialog.ShowError(err, am.window)
 		return
 	}
+	if err := am.reloadUsage(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read usage data: %v\n", err)
+	}
 	am.refreshList()
 }
 
 // saveAndReload removed: saving occurs immediately when aliases are added/edited/deleted
 
 func main() {
+	args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n%s", err, cliUsage)
+		os.Exit(2)
+	}
+	if len(args) > 0 {
+		os.Exit(runCLI(args, os.Stdout, os.Stderr))
+	}
+
 	a := app.New()
 	// Set embedded app icon when available
 	if len(iconSVG) > 0 {
@@ -710,11 +816,23 @@ func main() {
 	w := a.NewWindow("Bash Alias Manager")
 
 	am := &AliasManager{window: w, selectedIndex: -1}
-	err := am.loadAliases()
+	if am.queue, err = windowQueue(w); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// config is loaded first as it selects the target shell and alias file
+	err = am.loadConfig()
+	if err != nil {
+		dialog.ShowError(err, w)
+	}
+	home, _ := homeDir()
+	rcFile := "~/." + am.config.targetShell() + "rc"
+
+	err = am.loadAliases()
 	if err != nil {
 		if err.Error() == "permission-denied" {
 			// Snap confined: ask user to import their aliases via file chooser
-			resp := dialog.NewConfirm("Permission Denied", "Cannot access ~/.bash_aliases due to sandboxing. Would you like to select the file to import?", func(confirmed bool) {
+			resp := dialog.NewConfirm("Permission Denied", fmt.Sprintf("Cannot access %s due to sandboxing. Would you like to select the file to import?", displayPath(am.aliasFile(home), home)), func(confirmed bool) {
 				if confirmed {
 					am.promptForAliasFile()
 				}
@@ -725,12 +843,12 @@ func main() {
 		}
 	}
 
-	// Try to ensure .bashrc sources .bash_aliases, but if permission denied, instruct user
-	if err := am.ensureBashrcSources(); err != nil {
+	// Try to ensure the startup files source the alias file, but if permission denied, instruct user
+	if err := am.ensureShellIntegration(); err != nil {
 		if os.IsPermission(err) {
-			d := dialog.NewConfirm("Permission Denied", "Cannot edit ~/.bashrc due to sandboxing. To ensure aliases are loaded, please add the following lines to your ~/.bashrc manually:\n\nif [ -f ~/.bash_aliases ]; then\n    . ~/.bash_aliases\nfi\n\nWould you like to open the .bashrc file to edit it?", func(confirmed bool) {
+			d := dialog.NewConfirm("Permission Denied", fmt.Sprintf("Cannot edit %s due to sandboxing. To ensure aliases are loaded, please add the following line to your %s manually:\n\neval \"$(bash-alias-manager init %s)\"\n\nWould you like to open the %s file to edit it?", rcFile, rcFile, am.config.targetShell(), rcFile), func(confirmed bool) {
 				if confirmed {
-					// Let user select the .bashrc file via portal
+					// Let user select the rc file via portal
 					fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
 						if err != nil || reader == nil {
 							return
@@ -738,7 +856,7 @@ func main() {
 						defer reader.Close()
 						// We open the file for the user to edit in their editor manually; we don't write it ourselves
 					}, w)
-					fd.SetFilter(storage.NewExtensionFileFilter([]string{"bashrc", "sh", "txt"}))
+					fd.SetFilter(storage.NewExtensionFileFilter([]string{"bashrc", "zshrc", "sh", "txt"}))
 					fd.Show()
 				}
 			}, w)
@@ -746,20 +864,32 @@ func main() {
 		}
 	}
 
-	err = am.loadConfig()
-	if err != nil {
-		dialog.ShowError(err, w)
+	if err := am.reloadUsage(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read usage data: %v\n", err)
 	}
 
+	// move a token from an older plain text config to secure storage once
+	// the window is shown, as it may ask for a passphrase
+	a.Lifecycle().SetOnStarted(am.migrateToken)
+
 	am.list = widget.NewList(
 		func() int {
 			return len(am.aliases)
 		},
 		func() fyne.CanvasObject {
-			return widget.NewLabel("template")
+			return container.NewBorder(nil, nil, nil, widget.NewLabel("usage"), widget.NewLabel("template"))
 		},
 		func(i widget.ListItemID, o fyne.CanvasObject) {
-			o.(*widget.Label).SetText(fmt.Sprintf("%s = %s", am.aliases[i].Name, am.aliases[i].Command))
+			row := o.(*fyne.Container)
+			text := fmt.Sprintf("%s = %s", am.aliases[i].Name, am.aliases[i].Command)
+			if am.aliases[i].Type != "" {
+				text += fmt.Sprintf("  (%s)", am.aliases[i].Type)
+			}
+			if src := am.sourceLabel(am.aliases[i]); src != "" {
+				text += fmt.Sprintf("  [%s]", src)
+			}
+			row.Objects[0].(*widget.Label).SetText(text)
+			row.Objects[1].(*widget.Label).SetText(am.usageText(am.aliases[i].Name))
 		},
 	)
 
@@ -780,11 +910,16 @@ func main() {
 	})
 	// Save button removed (save happens automatically when editing/adding/removing aliases)
 	reloadBtn := widget.NewButton("Reload", am.reloadAliases)
-	backupBtn := widget.NewButton("Backup", am.backupToGist)
-	restoreBtn := widget.NewButton("Restore", am.restoreFromGist)
+	backupBtn := widget.NewButton("Backup", am.backupAliases)
+	restoreBtn := widget.NewButton("Restore", am.restoreAliases)
+	historyBtn := widget.NewButton("History", am.showBackupHistory)
+	suggestBtn := widget.NewButton("Suggestions", am.showSuggestions)
+	unusedBtn := widget.NewButton("Unused", am.showUnusedReport)
+	exportBtn := widget.NewButton("Export", am.showExport)
+	settingsBtn := widget.NewButton("Settings", am.showSettings)
 	aboutBtn := widget.NewButton("About", am.showAbout)
 
-	buttonBox := container.NewHBox(addBtn, editBtn, deleteBtn, reloadBtn, backupBtn, restoreBtn, aboutBtn)
+	buttonBox := container.NewHBox(addBtn, editBtn, deleteBtn, reloadBtn, suggestBtn, unusedBtn, exportBtn, backupBtn, restoreBtn, historyBtn, settingsBtn, aboutBtn)
 
 	w.SetContent(container.NewBorder(
 		nil,
@@ -794,6 +929,9 @@ func main() {
 		am.list,
 	))
 
+	// a backup not sent before the app last quit
+	am.resumePendingBackup()
+
 	w.Resize(fyne.NewSize(600, 400))
 	w.ShowAndRun()
 }
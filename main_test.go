@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestFormatAliasRoundTrip(t *testing.T) {
+	for _, a := range []Alias{
+		{Name: "ll", Command: "ls -l"},
+		{Name: "gc", Command: "git commit -m 'wip'"},
+		{Name: "q", Command: `echo "it's $HOME" \ done`},
+		{Name: "G", Command: "| grep", Type: "global"},
+		{Name: "pdf", Command: "evince", Type: "suffix"},
+	} {
+		line := formatAlias(a)
+		got, ok := parseAliasLine(line)
+		if !ok || got != a {
+			t.Errorf("%s: parsed back as %+v", line, got)
+		}
+	}
+}
+
+func TestParseAliasLineQuoting(t *testing.T) {
+	for line, want := range map[string]string{
+		`alias a='ls -l'`:             "ls -l",
+		`alias a="echo \"hi\" \$x"`:   `echo "hi" $x`,
+		`alias a='it'\''s'`:           "it's",
+		`alias a=ls`:                  "ls",
+		`alias a='unterminated`:       "unterminated",
+		`alias a="a 'single' inside"`: "a 'single' inside",
+	} {
+		got, ok := parseAliasLine(line)
+		if !ok || got.Command != want {
+			t.Errorf("%s: got %q, want %q", line, got.Command, want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifestSchema is the version of the manifest format written by this
+// version. Fields may be added without changing it; it is only raised for
+// changes older versions cannot read, which then restore from the alias file.
+const manifestSchema = 1
+
+// aliasManifest is the structured alias model stored next to the alias file
+// in a backup. The alias file alone loses which file each alias came from,
+// the usage statistics and the order across files.
+type aliasManifest struct {
+	Schema  int       `json:"schema"`
+	App     string    `json:"app"`
+	Version string    `json:"version"`
+	Created time.Time `json:"created"`
+	Shell   string    `json:"shell"`
+	// ContentSHA256 is the hex SHA-256 of the alias file saved with the
+	// manifest, so that a manifest is only used with the file it belongs to
+	ContentSHA256 string          `json:"content_sha256"`
+	Aliases       []manifestAlias `json:"aliases"`
+}
+
+// manifestAlias is one alias of the manifest, in list order
+type manifestAlias struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Type    string `json:"type,omitempty"`
+	// Source is the alias file, relative to the home directory as "~/..."
+	// when inside it; empty means the main alias file
+	Source   string     `json:"source,omitempty"`
+	Uses     int        `json:"uses,omitempty"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// manifestName returns the manifest file stored next to the alias file name
+func manifestName(file string) string {
+	return file + ".manifest.json"
+}
+
+// contentHash returns the hex SHA-256 of alias file content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// buildManifest returns the manifest of the current aliases, to be saved
+// with the alias file content
+func (am *AliasManager) buildManifest(content []byte) ([]byte, error) {
+	home, err := homeDir()
+	if err != nil {
+		return nil, err
+	}
+	main := am.aliasFile(home)
+	m := aliasManifest{
+		Schema:        manifestSchema,
+		App:           appDirName,
+		Version:       Version,
+		Created:       time.Now().UTC(),
+		Shell:         am.config.targetShell(),
+		ContentSHA256: contentHash(content),
+		Aliases:       []manifestAlias{},
+	}
+	for _, a := range am.aliases {
+		ma := manifestAlias{Name: a.Name, Command: a.Command, Type: a.Type}
+		if a.Source != "" && a.Source != main {
+			ma.Source = displayPath(a.Source, home)
+		}
+		if u, ok := am.usage[a.Name]; ok {
+			ma.Uses = u.Count
+			if !u.LastUsed.IsZero() {
+				t := u.LastUsed.UTC()
+				ma.LastUsed = &t
+			}
+		}
+		m.Aliases = append(m.Aliases, ma)
+	}
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// parseManifest reads a manifest, rejecting schemas this version cannot read
+func parseManifest(data []byte) (*aliasManifest, error) {
+	var m aliasManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if m.Schema < 1 || m.Schema > manifestSchema {
+		return nil, fmt.Errorf("manifest schema %d is not supported by this version", m.Schema)
+	}
+	return &m, nil
+}
+
+// matches checks that the manifest was saved with the alias file content.
+// A manifest without a hash cannot be checked and does not match.
+func (m *aliasManifest) matches(content []byte) error {
+	if m.ContentSHA256 != contentHash(content) {
+		return fmt.Errorf("the manifest does not belong to the alias file of this backup")
+	}
+	return nil
+}
+
+// aliases returns the manifest's aliases. Sources are kept when they are
+// alias files of this machine; aliases from other files go to the main file.
+func (m *aliasManifest) aliases(home string, sources []string) []Alias {
+	known := map[string]bool{}
+	for _, s := range sources {
+		known[s] = true
+	}
+	var aliases []Alias
+	for _, ma := range m.Aliases {
+		a := Alias{Name: ma.Name, Command: ma.Command, Type: ma.Type}
+		if ma.Source != "" {
+			if src := expandPath(ma.Source, home); known[src] {
+				a.Source = src
+			}
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases
+}
+
+// usage returns the usage statistics recorded in the manifest
+func (m *aliasManifest) usage() map[string]AliasUsage {
+	usage := map[string]AliasUsage{}
+	for _, ma := range m.Aliases {
+		if ma.Uses == 0 && ma.LastUsed == nil {
+			continue
+		}
+		u := AliasUsage{Count: ma.Uses}
+		if ma.LastUsed != nil {
+			u.LastUsed = ma.LastUsed.Local()
+		}
+		usage[ma.Name] = u
+	}
+	return usage
+}
+
+// loadRestoredUsage reads the usage statistics restored from backups
+func loadRestoredUsage(home string) map[string]AliasUsage {
+	usage := map[string]AliasUsage{}
+	data, err := os.ReadFile(restoredUsagePath(home))
+	if err == nil {
+		json.Unmarshal(data, &usage)
+	}
+	return usage
+}
+
+// saveRestoredUsage adds usage statistics of restored aliases to those
+// counted locally, replacing earlier restored values of the same aliases
+func saveRestoredUsage(home string, restored map[string]AliasUsage) error {
+	if len(restored) == 0 {
+		return nil
+	}
+	usage := loadRestoredUsage(home)
+	for name, u := range restored {
+		usage[name] = u
+	}
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := restoredUsagePath(home)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// addRestoredUsage adds restored statistics to usage computed locally
+func addRestoredUsage(usage, restored map[string]AliasUsage) {
+	for name, r := range restored {
+		u, ok := usage[name]
+		if !ok {
+			// no longer an alias
+			continue
+		}
+		u.Count += r.Count
+		if r.LastUsed.After(u.LastUsed) {
+			u.LastUsed = r.LastUsed
+		}
+		usage[name] = u
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// objectStore is storage holding named objects without history of its own,
+// such as a WebDAV collection or an S3 bucket
+type objectStore interface {
+	// put stores data under name
+	put(ctx context.Context, name string, data []byte) error
+	// get returns the object name, or errNoBackup when it does not exist
+	get(ctx context.Context, name string) ([]byte, error)
+	// list returns the names of the objects starting with prefix
+	list(ctx context.Context, prefix string) ([]string, error)
+}
+
+// objectStampFormat names backup objects; it sorts chronologically
+const objectStampFormat = "20060102T150405.000Z"
+
+// objectProvider versions backups in an objectStore by writing every backup
+// as a new object named after gistFileName and the time it was made, e.g.
+// bash_aliases.20240102T030405.000Z. The newest object is the latest backup.
+type objectProvider struct {
+	name  string
+	store objectStore
+}
+
+// backupObjectName returns the object name of a backup made at t
+func backupObjectName(t time.Time) string {
+	return gistFileName + "." + t.UTC().Format(objectStampFormat)
+}
+
+// backupObjects returns the names of the backup objects, newest first
+func (p *objectProvider) backupObjects(ctx context.Context) ([]string, error) {
+	names, err := p.store.list(ctx, gistFileName+".")
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, n := range names {
+		if _, err := time.Parse(objectStampFormat, strings.TrimPrefix(n, gistFileName+".")); err == nil {
+			backups = append(backups, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+func (p *objectProvider) Name() string {
+	return p.name
+}
+
+func (p *objectProvider) Save(ctx context.Context, content []byte) error {
+	return p.store.put(ctx, backupObjectName(time.Now()), content)
+}
+
+func (p *objectProvider) Load(ctx context.Context) ([]byte, error) {
+	backups, err := p.backupObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, errNoBackup
+	}
+	return p.store.get(ctx, backups[0])
+}
+
+func (p *objectProvider) Revisions(ctx context.Context, limit int) ([]BackupRevision, error) {
+	backups, err := p.backupObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) > limit {
+		backups = backups[:limit]
+	}
+	var revisions []BackupRevision
+	for _, name := range backups {
+		content, err := p.store.get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		stamp := strings.TrimPrefix(name, gistFileName+".")
+		t, _ := time.Parse(objectStampFormat, stamp)
+		revisions = append(revisions, BackupRevision{
+			Version: stamp,
+			Time:    t.Local().Format("2006-01-02 15:04"),
+			Content: content,
+			Aliases: aliasCount(content),
+		})
+	}
+	return revisions, nil
+}
+
+// objectRequest sends req, retrying transient failures, and returns the
+// response body. A non-2xx status is returned as a forgeError carrying the
+// server's message, if any.
+func objectRequest(req *http.Request) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// S3 and some WebDAV servers answer with <Error><Message>...</Message></Error>
+		var msg struct {
+			Message string `xml:"Message"`
+		}
+		fe := &forgeError{Status: resp.StatusCode}
+		if xml.Unmarshal(data, &msg) == nil {
+			fe.Message = msg.Message
+		}
+		return nil, fe
+	}
+	return data, nil
+}
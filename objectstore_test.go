@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The examples of the AWS documentation "Authenticating Requests: Using the
+// Authorization Header (AWS Signature Version 4)"
+const (
+	awsExampleSecret = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+	awsExampleEmpty  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+func TestSigV4AWSExamples(t *testing.T) {
+	now := time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC)
+
+	// GET Object, which signs a Range header as well
+	getObject := "GET\n/test.txt\n\n" +
+		"host:examplebucket.s3.amazonaws.com\nrange:bytes=0-9\n" +
+		"x-amz-content-sha256:" + awsExampleEmpty + "\nx-amz-date:20130524T000000Z\n\n" +
+		"host;range;x-amz-content-sha256;x-amz-date\n" + awsExampleEmpty
+	scope, sig := sigV4Signature(awsExampleSecret, "us-east-1", "s3", now, getObject)
+	if scope != "20130524/us-east-1/s3/aws4_request" {
+		t.Errorf("scope = %q", scope)
+	}
+	if want := "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41"; sig != want {
+		t.Errorf("GET Object signature = %s, want %s", sig, want)
+	}
+
+	// GET Bucket (List Objects), built by sigV4Canonical
+	list := sigV4Canonical("GET", "/", "max-keys=2&prefix=J", "examplebucket.s3.amazonaws.com", awsExampleEmpty, "20130524T000000Z")
+	if _, sig := sigV4Signature(awsExampleSecret, "us-east-1", "s3", now, list); sig != "34b48302e7b5fa45bde8084f4b7868a86f0a534bc59db6670ed5711ef69dc6f7" {
+		t.Errorf("List Objects signature = %s", sig)
+	}
+}
+
+// fakeS3 is an S3 API serving one bucket below a path prefix. It checks the
+// signature of every request and returns list results in pages of two.
+type fakeS3 struct {
+	secret  string
+	base    string
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	_, sig, _ := strings.Cut(auth, "Signature=")
+	canonical := sigV4Canonical(r.Method, r.URL.EscapedPath(), r.URL.RawQuery, r.Host,
+		r.Header.Get("X-Amz-Content-Sha256"), r.Header.Get("X-Amz-Date"))
+	now, _ := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if _, want := sigV4Signature(f.secret, "us-east-1", "s3", now, canonical); sig != want {
+		w.WriteHeader(403)
+		fmt.Fprint(w, "<Error><Message>SignatureDoesNotMatch</Message></Error>")
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, f.base) {
+		w.WriteHeader(404)
+		return
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, f.base), "/")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == "PUT":
+		body, _ := io.ReadAll(r.Body)
+		if sum := sha256.Sum256(body); hex.EncodeToString(sum[:]) != r.Header.Get("X-Amz-Content-Sha256") {
+			w.WriteHeader(400)
+			return
+		}
+		f.objects[key] = body
+	case r.Method == "GET" && key == "":
+		f.list(w, r.URL.Query())
+	case r.Method == "GET":
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(405)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, q url.Values) {
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, q.Get("prefix")) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	start, _ := strconv.Atoi(q.Get("continuation-token"))
+	var result s3ListResult
+	for i := start; i < len(keys) && i < start+2; i++ {
+		result.Contents = append(result.Contents, struct {
+			Key string `xml:"Key"`
+		}{keys[i]})
+	}
+	if start+2 < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = strconv.Itoa(start + 2)
+	}
+	data, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		s3ListResult
+	}{s3ListResult: result})
+	w.Write(data)
+}
+
+// fakeWebDAV is a WebDAV server with one collection, created by MKCOL
+type fakeWebDAV struct {
+	collection string
+	mu         sync.Mutex
+	exists     bool
+	files      map[string][]byte
+}
+
+func (f *fakeWebDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "me" || pass != "secret" {
+		w.WriteHeader(401)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := strings.TrimPrefix(r.URL.Path, f.collection)
+	switch r.Method {
+	case "MKCOL":
+		f.exists = true
+		w.WriteHeader(201)
+	case "PUT":
+		if !f.exists {
+			w.WriteHeader(409)
+			return
+		}
+		f.files[name], _ = io.ReadAll(r.Body)
+		w.WriteHeader(201)
+	case "GET":
+		data, ok := f.files[name]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write(data)
+	case "PROPFIND":
+		if !f.exists {
+			w.WriteHeader(404)
+			return
+		}
+		if r.Header.Get("Depth") != "1" {
+			w.WriteHeader(400)
+			return
+		}
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+		fmt.Fprintf(&b, "<d:response><d:href>%s</d:href></d:response>", f.collection)
+		for n := range f.files {
+			fmt.Fprintf(&b, "<d:response><d:href>%s%s</d:href></d:response>", f.collection, url.PathEscape(n))
+		}
+		b.WriteString("</d:multistatus>")
+		w.WriteHeader(207)
+		w.Write([]byte(b.String()))
+	default:
+		w.WriteHeader(405)
+	}
+}
+
+// checkObjectProvider backs up three times, then restores and lists the
+// backups
+func checkObjectProvider(t *testing.T, p *objectProvider) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := p.Load(ctx); !errors.Is(err, errNoBackup) {
+		t.Fatalf("Load before the first backup: %v, want errNoBackup", err)
+	}
+	if revs, err := p.Revisions(ctx, 10); err != nil || len(revs) != 0 {
+		t.Fatalf("Revisions before the first backup = %v, %v", revs, err)
+	}
+	backups := []string{"alias a='1'\n", "alias a='1'\nalias b='2'\n", "alias c='3'\n"}
+	for _, b := range backups {
+		if err := p.Save(ctx, []byte(b)); err != nil {
+			t.Fatal(err)
+		}
+		// object names have millisecond precision
+		time.Sleep(2 * time.Millisecond)
+	}
+	content, err := p.Load(ctx)
+	if err != nil || string(content) != backups[2] {
+		t.Fatalf("Load = %q, %v; want the latest backup", content, err)
+	}
+	revs, err := p.Revisions(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 || string(revs[0].Content) != backups[2] || string(revs[1].Content) != backups[1] || revs[1].Aliases != 2 {
+		t.Errorf("Revisions = %+v", revs)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{secret: "topsecret", base: "/minio/backups", objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	store := &s3Store{
+		endpoint:  srv.URL + "/minio",
+		region:    "us-east-1",
+		bucket:    "backups",
+		prefix:    "team/",
+		accessKey: "AKID",
+		secretKey: "topsecret",
+	}
+	// an unrelated object next to the backups
+	fake.objects["team/notes.txt"] = []byte("x")
+	checkObjectProvider(t, &objectProvider{name: "S3", store: store})
+	for k := range fake.objects {
+		if !strings.HasPrefix(k, "team/") {
+			t.Errorf("object %q stored outside the key prefix", k)
+		}
+	}
+
+	store.secretKey = "wrong"
+	if _, err := store.get(context.Background(), "x"); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("wrong secret key: %v", err)
+	}
+}
+
+func TestWebDAVStore(t *testing.T) {
+	fake := &fakeWebDAV{collection: "/dav/aliases/", files: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	store := &webdavStore{base: srv.URL + fake.collection, user: "me", password: "secret"}
+	checkObjectProvider(t, &objectProvider{name: "WebDAV", store: store})
+	if len(fake.files) != 3 {
+		t.Errorf("%d files stored, want 3", len(fake.files))
+	}
+
+	store.password = "wrong"
+	if _, err := store.list(context.Background(), gistFileName); err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("wrong password: %v", err)
+	}
+}
+
+func TestObjectStoreRetriesUnavailable(t *testing.T) {
+	fake := &fakeWebDAV{collection: "/dav/", exists: true, files: map[string][]byte{"a": []byte("alias a='1'\n")}}
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(503)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	store := &webdavStore{base: srv.URL + fake.collection, user: "me", password: "secret"}
+	if data, err := store.get(context.Background(), "a"); err != nil || string(data) != "alias a='1'\n" {
+		t.Errorf("get = %q, %v", data, err)
+	}
+	if requests != 2 {
+		t.Errorf("%d requests, want 2", requests)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+const (
+	// pendingRetryDelay is the first wait before a pending backup is sent
+	// again; it doubles up to pendingRetryMax while the server stays away
+	pendingRetryDelay = 15 * time.Second
+	pendingRetryMax   = 5 * time.Minute
+)
+
+// pendingBackup is a backup that could not be sent because the server was
+// unreachable or rate-limited. It is kept, already encrypted when enabled,
+// and sent again in the background until it succeeds. A copy in the data
+// directory is sent when the app starts again before that.
+type pendingBackup struct {
+	provider BackupProvider
+	// target is the ID of the backup target and gist the name of the Gist
+	// target, to open the provider again after a restart
+	target   string
+	gist     string
+	content  []byte
+	manifest []byte
+	// err is why the last attempt failed
+	err error
+}
+
+// pendingBackupFile is the copy of a pending backup kept between runs
+type pendingBackupFile struct {
+	Target   string `json:"target"`
+	Gist     string `json:"gist,omitempty"`
+	Name     string `json:"name"`
+	Content  []byte `json:"content"`
+	Manifest []byte `json:"manifest,omitempty"`
+}
+
+// storePendingBackup writes the copy of pb kept between runs, or removes it
+// when pb is nil. Failures are reported but keep the backup in memory.
+func storePendingBackup(pb *pendingBackup) {
+	home, err := homeDir()
+	if err == nil {
+		err = writePendingBackup(pendingBackupPath(home), pb)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to store the pending backup: %v\n", err)
+	}
+}
+
+// writePendingBackup writes pb to path, readable only by the user as it may
+// hold the aliases in plain text, or removes path when pb is nil
+func writePendingBackup(path string, pb *pendingBackup) error {
+	if pb == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(pendingBackupFile{
+		Target:   pb.target,
+		Gist:     pb.gist,
+		Name:     pb.provider.Name(),
+		Content:  pb.content,
+		Manifest: pb.manifest,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// resumePendingBackup sends the backup that was still pending when the app
+// last quit, opening its provider the way the backup did
+func (am *AliasManager) resumePendingBackup() {
+	home, err := homeDir()
+	if err != nil {
+		return
+	}
+	path := pendingBackupPath(home)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	var saved pendingBackupFile
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to read the backup not sent before quitting: %v", err), am.window)
+		return
+	}
+	queue := func(p BackupProvider) {
+		am.queueBackup(&pendingBackup{provider: p, target: saved.Target, gist: saved.Gist, content: saved.Content, manifest: saved.Manifest})
+	}
+	for _, t := range backupTargets {
+		if t.ID != saved.Target {
+			continue
+		}
+		if t.ID != "gist" {
+			t.open(am, queue)
+			return
+		}
+		for i, gt := range am.config.gistTargets() {
+			if gt.Name == saved.Gist {
+				openGistTarget(am, i, gt, queue)
+				return
+			}
+		}
+	}
+	// the target was removed from the settings
+	storePendingBackup(nil)
+	dialog.ShowInformation("Backup", fmt.Sprintf("A backup to %s was not sent before the app quit, and that target no longer exists. Back up again to send your aliases.", saved.Name), am.window)
+}
+
+// isTemporary reports whether a backup that failed with err should be kept
+// and sent again later
+func isTemporary(err error) bool {
+	_, limited := rateLimitReset(err)
+	return limited || isUnreachable(err)
+}
+
+// saveBackup stores content with p, together with the manifest when p
+// stores one
+func saveBackup(ctx context.Context, p BackupProvider, content, manifest []byte) error {
+	if mp, ok := p.(manifestProvider); ok {
+		return mp.SaveWithManifest(ctx, content, manifest)
+	}
+	return p.Save(ctx, content)
+}
+
+// queueBackup keeps a backup that failed with a temporary error and starts
+// sending it again in the background. A newer backup replaces a pending one.
+func (am *AliasManager) queueBackup(pb *pendingBackup) {
+	am.pendingMu.Lock()
+	defer am.pendingMu.Unlock()
+	am.pending = pb
+	storePendingBackup(pb)
+	if !am.pendingRetrying {
+		am.pendingRetrying = true
+		go am.retryPendingBackup()
+	}
+}
+
+// dropPendingBackup forgets the pending backup to p once a newer backup was
+// sent there
+func (am *AliasManager) dropPendingBackup(p BackupProvider) {
+	am.pendingMu.Lock()
+	if am.pending != nil && am.pending.provider.Name() == p.Name() {
+		am.pending = nil
+		storePendingBackup(nil)
+	}
+	am.pendingMu.Unlock()
+}
+
+// preemptPendingBackup cancels a running attempt of sending the pending
+// backup, which is tried again later, so that an operation the user started
+// does not wait for it
+func (am *AliasManager) preemptPendingBackup() {
+	am.pendingMu.Lock()
+	if am.pendingCancel != nil {
+		am.pendingCancel()
+	}
+	am.pendingMu.Unlock()
+}
+
+// nextPendingBackup returns the pending backup, or nil after which the
+// caller stops retrying
+func (am *AliasManager) nextPendingBackup() *pendingBackup {
+	am.pendingMu.Lock()
+	defer am.pendingMu.Unlock()
+	if am.pending == nil {
+		am.pendingRetrying = false
+	}
+	return am.pending
+}
+
+// pendingWait returns the wait before attempt of sending a pending backup,
+// or until a rate limit resets. A backup resumed at startup, which has not
+// failed yet, is sent at once.
+func pendingWait(err error, attempt int) time.Duration {
+	if err == nil && attempt == 0 {
+		return 0
+	}
+	if reset, ok := rateLimitReset(err); ok {
+		if wait := time.Until(reset) + time.Second; wait > pendingRetryDelay {
+			return wait
+		}
+		return pendingRetryDelay
+	}
+	wait := pendingRetryDelay << attempt
+	if wait > pendingRetryMax || wait <= 0 {
+		wait = pendingRetryMax
+	}
+	return wait
+}
+
+// retryPendingBackup sends the pending backup until it succeeds, fails for
+// good or is dropped. It does not hold networkBusy: an attempt is skipped
+// while the user runs a network operation, and one starting meanwhile
+// cancels it (see preemptPendingBackup).
+func (am *AliasManager) retryPendingBackup() {
+	for attempt := 0; ; attempt++ {
+		pb := am.nextPendingBackup()
+		if pb == nil {
+			return
+		}
+		time.Sleep(pendingWait(pb.err, attempt))
+		if pb = am.nextPendingBackup(); pb == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), networkTimeout)
+		am.pendingMu.Lock()
+		am.pendingCancel = cancel
+		am.pendingMu.Unlock()
+		// checked after publishing cancel, so that an operation starting
+		// now either is seen here or cancels the attempt
+		if networkBusy.Load() {
+			cancel()
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = saveBackup(ctx, pb.provider, pb.content, pb.manifest)
+		}
+		preempted := ctx.Err() == context.Canceled
+		cancel()
+
+		am.pendingMu.Lock()
+		am.pendingCancel = nil
+		if am.pending != pb {
+			// replaced by a newer backup meanwhile
+			am.pendingMu.Unlock()
+			attempt = -1
+			continue
+		}
+		if err != nil && (isTemporary(err) || preempted) {
+			if isTemporary(err) {
+				pb.err = err
+			}
+			am.pendingMu.Unlock()
+			fmt.Fprintf(os.Stderr, "Pending backup to %s not sent yet: %v\n", pb.provider.Name(), err)
+			continue
+		}
+		am.pending = nil
+		am.pendingRetrying = false
+		storePendingBackup(nil)
+		am.pendingMu.Unlock()
+		am.onUI(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to send the pending backup to %s: %v", pb.provider.Name(), err), am.window)
+				return
+			}
+			fyne.CurrentApp().SendNotification(fyne.NewNotification("Backup", fmt.Sprintf("The pending backup was sent to %s.", pb.provider.Name())))
+		})
+		return
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// useBackupTarget registers a target opening p for the rest of the test
+func useBackupTarget(t *testing.T, id string, p BackupProvider) {
+	targets := backupTargets
+	backupTargets = append([]backupTarget{{ID: id, Label: id, open: func(_ *AliasManager, fn func(BackupProvider)) { fn(p) }}}, targets...)
+	t.Cleanup(func() { backupTargets = targets })
+}
+
+// readPendingBackup returns the stored copy of the pending backup, or nil
+func readPendingBackup(t *testing.T, home string) *pendingBackupFile {
+	t.Helper()
+	data, err := os.ReadFile(pendingBackupPath(home))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	var saved pendingBackupFile
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &saved
+}
+
+func TestPendingWait(t *testing.T) {
+	offline := &unreachableError{host: "api.github.com", err: errors.New("no route")}
+	limited := &rateLimitStatusError{reset: time.Now().Add(time.Hour)}
+	soon := &rateLimitStatusError{reset: time.Now().Add(time.Second)}
+	for _, tt := range []struct {
+		name     string
+		err      error
+		attempt  int
+		min, max time.Duration
+	}{
+		{name: "resumed at startup", err: nil, attempt: 0, min: 0, max: 0},
+		{name: "first retry", err: offline, attempt: 0, min: pendingRetryDelay, max: pendingRetryDelay},
+		{name: "doubled", err: offline, attempt: 2, min: 4 * pendingRetryDelay, max: 4 * pendingRetryDelay},
+		{name: "capped", err: offline, attempt: 10, min: pendingRetryMax, max: pendingRetryMax},
+		{name: "no overflow", err: offline, attempt: 70, min: pendingRetryMax, max: pendingRetryMax},
+		{name: "preempted later", err: nil, attempt: 1, min: 2 * pendingRetryDelay, max: 2 * pendingRetryDelay},
+		{name: "until the rate limit resets", err: limited, attempt: 3, min: time.Hour, max: time.Hour + time.Second},
+		{name: "rate limit ending soon", err: soon, attempt: 0, min: pendingRetryDelay, max: pendingRetryDelay},
+	} {
+		if wait := pendingWait(tt.err, tt.attempt); wait < tt.min || wait > tt.max {
+			t.Errorf("%s: %v, want %v to %v", tt.name, wait, tt.min, tt.max)
+		}
+	}
+}
+
+func TestQueueBackup(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	am := &AliasManager{}
+	// a retry loop is running already, so none is started
+	am.pendingRetrying = true
+	gist := &memoryManifestProvider{}
+	offline := &unreachableError{host: "api.github.com", err: errors.New("no route")}
+
+	am.queueBackup(&pendingBackup{provider: gist, target: "gist", gist: "work", content: []byte("first"), manifest: []byte("{}"), err: offline})
+	saved := readPendingBackup(t, home)
+	if saved == nil || saved.Target != "gist" || saved.Gist != "work" || saved.Name != "memory" || string(saved.Content) != "first" || string(saved.Manifest) != "{}" {
+		t.Fatalf("stored %+v", saved)
+	}
+	if fi, err := os.Stat(pendingBackupPath(home)); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("pending backup mode %v, %v", fi.Mode(), err)
+	}
+
+	// a newer backup replaces the pending one
+	am.queueBackup(&pendingBackup{provider: gist, target: "gist", gist: "work", content: []byte("second"), err: offline})
+	if saved := readPendingBackup(t, home); saved == nil || string(saved.Content) != "second" || saved.Manifest != nil {
+		t.Errorf("after replacing: %+v", saved)
+	}
+	if string(am.pending.content) != "second" {
+		t.Errorf("pending %q", am.pending.content)
+	}
+
+	// a backup sent to another provider leaves it alone
+	am.dropPendingBackup(&namedProvider{name: "other"})
+	if am.pending == nil || readPendingBackup(t, home) == nil {
+		t.Error("dropped by a backup to another provider")
+	}
+	// one sent to the same provider makes it obsolete
+	am.dropPendingBackup(gist)
+	if am.pending != nil || readPendingBackup(t, home) != nil {
+		t.Error("not dropped after a newer backup")
+	}
+}
+
+// namedProvider is a memoryProvider with another name
+type namedProvider struct {
+	memoryProvider
+	name string
+}
+
+func (p *namedProvider) Name() string { return p.name }
+
+func TestResumePendingBackup(t *testing.T) {
+	am, events := newProgressTest(t)
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	p := &memoryManifestProvider{}
+	useBackupTarget(t, "memory", p)
+	// left by the last run
+	err := writePendingBackup(pendingBackupPath(home), &pendingBackup{provider: p, target: "memory", content: []byte("alias a='1'\n"), manifest: []byte("{}")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	am.resumePendingBackup()
+	// the notification of the backup sent
+	runNextEvent(t, events)
+	if len(p.revisions) != 1 || string(p.revisions[0].Content) != "alias a='1'\n" || string(p.revisions[0].Manifest) != "{}" {
+		t.Errorf("sent %+v", p.revisions)
+	}
+	if readPendingBackup(t, home) != nil || am.pending != nil {
+		t.Error("pending backup kept after it was sent")
+	}
+	// nothing is left to send on the next start
+	am.resumePendingBackup()
+	if len(p.revisions) != 1 || am.window.Canvas().Overlays().Top() != nil {
+		t.Error("resumed twice")
+	}
+}
+
+func TestResumePendingBackupRemovedTarget(t *testing.T) {
+	am, _ := newProgressTest(t)
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	am.config.GistTargets = []GistTarget{{Name: "personal"}}
+	p := &memoryProvider{}
+	for _, pb := range []*pendingBackup{
+		{provider: p, target: "gist", gist: "work", content: []byte("x")},
+		{provider: p, target: "dropbox", content: []byte("x")},
+	} {
+		if err := writePendingBackup(pendingBackupPath(home), pb); err != nil {
+			t.Fatal(err)
+		}
+		am.resumePendingBackup()
+		if readPendingBackup(t, home) != nil {
+			t.Errorf("%s %s: pending backup kept", pb.target, pb.gist)
+		}
+		if !overlayText(am, "no longer exists") {
+			t.Errorf("%s %s: not reported", pb.target, pb.gist)
+		}
+		am.window.Canvas().Overlays().Remove(am.window.Canvas().Overlays().Top())
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	// networkTimeout limits backups, restores and other network operations
+	networkTimeout = 2 * time.Minute
+	// updateTimeout limits downloading and installing an update
+	updateTimeout = 10 * time.Minute
+)
+
+// networkBusy is set while a background operation runs, so that a second one
+// (started from the menu, which the progress dialog does not cover) waits
+var networkBusy atomic.Bool
+
+// eventQueue is the event queue of a window: the desktop and mobile drivers
+// of Fyne v2.4, pinned in go.mod, handle a window's input events in order
+// on one goroutine and queue them with QueueEvent. Fyne 2.4 has no public
+// API for running code there, so check the method still exists when
+// upgrading; the app refuses to start without it.
+type eventQueue interface {
+	QueueEvent(fn func())
+}
+
+// windowQueue returns the function queueing events for w
+func windowQueue(w fyne.Window) (func(func()), error) {
+	q, ok := w.(eventQueue)
+	if !ok {
+		return nil, fmt.Errorf("the %T window has no event queue; this build needs fyne.io/fyne/v2 v2.4", w)
+	}
+	return q.QueueEvent, nil
+}
+
+// onUI runs fn on the goroutine handling the window's input events, where
+// button and dialog callbacks run, so that results of background work are
+// applied in order with them instead of racing them
+func (am *AliasManager) onUI(fn func()) {
+	if am.queue == nil {
+		panic("onUI: the window's event queue is not set up")
+	}
+	am.queue(fn)
+}
+
+// runInBackground runs work in a goroutine while a progress dialog showing
+// message is open. Its Cancel button and the timeout cancel the context
+// passed to work, which can update the message with status.
+//
+// work runs in its own goroutine and must not touch the manager: callers copy
+// what it needs beforehand. The dialog does not cover the menus, so the
+// aliases and settings may change while it runs. done is called on the UI
+// side (see onUI) with work's error once the dialog is gone, but not when
+// the user cancelled; it applies and shows the results.
+func (am *AliasManager) runInBackground(title, message string, timeout time.Duration, work func(ctx context.Context, status func(string)) error, done func(error)) {
+	if !networkBusy.CompareAndSwap(false, true) {
+		dialog.ShowInformation(title, "Please wait until the running operation has finished.", am.window)
+		return
+	}
+	am.preemptPendingBackup()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	label := widget.NewLabel(message)
+	// lets retries explain the wait
+	ctx = context.WithValue(ctx, statusKey{}, label.SetText)
+	d := dialog.NewCustom(title, "Cancel", container.NewVBox(label, widget.NewProgressBarInfinite()), am.window)
+	// called by Cancel as well as by Hide below
+	d.SetOnClosed(cancel)
+	d.Show()
+
+	go func() {
+		err := work(ctx, label.SetText)
+		networkBusy.Store(false)
+		switch ctx.Err() {
+		case context.Canceled:
+			// closed by the user
+			return
+		case context.DeadlineExceeded:
+			err = fmt.Errorf("timed out after %v", timeout)
+		}
+		am.onUI(func() {
+			d.Hide()
+			done(err)
+		})
+	}()
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newProgressTest returns a manager on a test window. Functions queued for
+// the window's event goroutine arrive on the returned channel.
+func newProgressTest(t *testing.T) (*AliasManager, chan func()) {
+	t.Helper()
+	test.NewApp()
+	events := make(chan func(), 10)
+	am := &AliasManager{window: test.NewWindow(nil), queue: func(fn func()) { events <- fn }}
+	return am, events
+}
+
+// runNextEvent runs the next function queued for the event goroutine
+func runNextEvent(t *testing.T, events chan func()) {
+	t.Helper()
+	select {
+	case fn := <-events:
+		fn()
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing was queued for the event goroutine")
+	}
+}
+
+// findObject returns the first object below o that match accepts
+func findObject(o fyne.CanvasObject, match func(fyne.CanvasObject) bool) fyne.CanvasObject {
+	if o == nil {
+		return nil
+	}
+	if match(o) {
+		return o
+	}
+	var children []fyne.CanvasObject
+	switch o := o.(type) {
+	case *fyne.Container:
+		children = o.Objects
+	case fyne.Widget:
+		children = test.WidgetRenderer(o).Objects()
+	}
+	for _, c := range children {
+		if found := findObject(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// overlayText reports whether the topmost dialog of am's window shows text
+func overlayText(am *AliasManager, text string) bool {
+	return findObject(am.window.Canvas().Overlays().Top(), func(o fyne.CanvasObject) bool {
+		l, ok := o.(*widget.Label)
+		return ok && strings.Contains(l.Text, text)
+	}) != nil
+}
+
+func TestWindowQueue(t *testing.T) {
+	test.NewApp()
+	// the test driver has no event queue, unlike the desktop one
+	if _, err := windowQueue(test.NewWindow(nil)); err == nil || !strings.Contains(err.Error(), "v2.4") {
+		t.Errorf("test window: err = %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("onUI without an event queue did not panic")
+		}
+	}()
+	(&AliasManager{}).onUI(func() {})
+}
+
+func TestRunInBackground(t *testing.T) {
+	am, events := newProgressTest(t)
+	failed := errors.New("failed")
+	for _, want := range []error{nil, failed} {
+		step := make(chan struct{})
+		var got error
+		called := false
+		am.runInBackground("Backup", "Sending…", time.Minute, func(ctx context.Context, status func(string)) error {
+			reportStatus(ctx, "retrying")
+			step <- struct{}{}
+			<-step
+			return want
+		}, func(err error) {
+			called, got = true, err
+		})
+		<-step
+		if !overlayText(am, "retrying") {
+			t.Error("status not shown in the progress dialog")
+		}
+		step <- struct{}{}
+		runNextEvent(t, events)
+		if !called || got != want {
+			t.Errorf("done(%v) called %v, want done(%v)", got, called, want)
+		}
+		if am.window.Canvas().Overlays().Top() != nil {
+			t.Error("progress dialog still open")
+		}
+		if networkBusy.Load() {
+			t.Error("still busy")
+		}
+	}
+}
+
+func TestRunInBackgroundTimeout(t *testing.T) {
+	am, events := newProgressTest(t)
+	var got error
+	am.runInBackground("Backup", "Sending…", 20*time.Millisecond, func(ctx context.Context, _ func(string)) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, func(err error) {
+		got = err
+	})
+	runNextEvent(t, events)
+	if got == nil || got.Error() != "timed out after 20ms" {
+		t.Errorf("done(%v), want the timeout", got)
+	}
+	if am.window.Canvas().Overlays().Top() != nil {
+		t.Error("progress dialog still open")
+	}
+}
+
+func TestRunInBackgroundCancel(t *testing.T) {
+	am, events := newProgressTest(t)
+	stopped := make(chan error, 1)
+	am.runInBackground("Backup", "Sending…", time.Minute, func(ctx context.Context, _ func(string)) error {
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return ctx.Err()
+	}, func(err error) {
+		t.Errorf("done(%v) called after Cancel", err)
+	})
+	cancel := findObject(am.window.Canvas().Overlays().Top(), func(o fyne.CanvasObject) bool {
+		b, ok := o.(*widget.Button)
+		return ok && b.Text == "Cancel"
+	})
+	if cancel == nil {
+		t.Fatal("no Cancel button")
+	}
+	test.Tap(cancel.(*widget.Button))
+	if err := <-stopped; err != context.Canceled {
+		t.Errorf("work stopped with %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); networkBusy.Load(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("still busy after Cancel")
+		}
+	}
+	select {
+	case <-events:
+		t.Error("a result was queued after Cancel")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestRunInBackgroundBusy(t *testing.T) {
+	am, events := newProgressTest(t)
+	release := make(chan struct{})
+	am.runInBackground("Backup", "Sending…", time.Minute, func(context.Context, func(string)) error {
+		<-release
+		return nil
+	}, func(error) {})
+
+	// a second operation, e.g. from the menu, is refused while one runs
+	am.runInBackground("Restore", "Loading…", time.Minute, func(context.Context, func(string)) error {
+		t.Error("second operation ran")
+		return nil
+	}, func(error) {
+		t.Error("second operation finished")
+	})
+	if !overlayText(am, "Please wait") {
+		t.Error("no explanation shown")
+	}
+	close(release)
+	runNextEvent(t, events)
+	if networkBusy.Load() {
+		t.Error("still busy")
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Kinds of difference between the local aliases and a backup
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// AliasChange is one difference between the local aliases and a backup.
+// Added aliases exist only in the backup, removed ones only locally.
+type AliasChange struct {
+	Kind   string
+	Name   string
+	Local  Alias
+	Backup Alias
+}
+
+// parseAliasContent parses alias file content without touching the manager
+func parseAliasContent(content []byte) ([]Alias, error) {
+	var aliases []Alias
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if alias, ok := parseAliasLine(scanner.Text()); ok {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases, scanner.Err()
+}
+
+// diffAliases compares aliases by name, listing changes in the order of the
+// local list followed by the aliases only found in the backup
+func diffAliases(local, backup []Alias) []AliasChange {
+	inBackup := map[string]Alias{}
+	for _, a := range backup {
+		inBackup[a.Name] = a
+	}
+	inLocal := map[string]bool{}
+	var changes []AliasChange
+	for _, l := range local {
+		inLocal[l.Name] = true
+		b, ok := inBackup[l.Name]
+		switch {
+		case !ok:
+			changes = append(changes, AliasChange{Kind: changeRemoved, Name: l.Name, Local: l})
+		case b.Command != l.Command || b.Type != l.Type:
+			changes = append(changes, AliasChange{Kind: changeChanged, Name: l.Name, Local: l, Backup: b})
+		}
+	}
+	for _, b := range backup {
+		if !inLocal[b.Name] {
+			inLocal[b.Name] = true
+			// the shell keeps the last definition of a name
+			changes = append(changes, AliasChange{Kind: changeAdded, Name: b.Name, Backup: inBackup[b.Name]})
+		}
+	}
+	return changes
+}
+
+// applyAliasChanges returns local with the given changes applied. Changed
+// aliases stay in the file they were in; added ones go to the main file.
+// Removals and changes only affect the alias in the file they were found
+// in, not one of the same name elsewhere.
+func applyAliasChanges(local []Alias, changes []AliasChange) []Alias {
+	type key struct{ name, source string }
+	byName := map[key]AliasChange{}
+	for _, c := range changes {
+		byName[key{c.Name, c.Local.Source}] = c
+	}
+	merged := []Alias{}
+	for _, a := range local {
+		c, ok := byName[key{a.Name, a.Source}]
+		switch {
+		case ok && c.Kind == changeRemoved:
+			continue
+		case ok && c.Kind == changeChanged:
+			a.Command, a.Type = c.Backup.Command, c.Backup.Type
+		}
+		merged = append(merged, a)
+	}
+	for _, c := range changes {
+		if c.Kind == changeAdded {
+			merged = append(merged, c.Backup)
+		}
+	}
+	return merged
+}
+
+// changeText describes a change for the restore preview
+func changeText(c AliasChange) string {
+	switch c.Kind {
+	case changeAdded:
+		return fmt.Sprintf("+ %s", formatAlias(c.Backup))
+	case changeRemoved:
+		return fmt.Sprintf("- %s", formatAlias(c.Local))
+	default:
+		return fmt.Sprintf("~ %s\n    was: %s", formatAlias(c.Backup), formatAlias(c.Local))
+	}
+}
+
+// restoreBackup decrypts a downloaded backup and previews restoring it
+func (am *AliasManager) restoreBackup(title string, content, manifest []byte) {
+	am.openBackup(content, func(content []byte) {
+		preview := func(manifest []byte) {
+			local, backup, usage, err := am.restoreSets(content, manifest)
+			if err != nil {
+				dialog.ShowError(err, am.window)
+				return
+			}
+			am.showRestorePreview(title, local, backup, usage)
+		}
+		if manifest == nil {
+			preview(nil)
+			return
+		}
+		am.openBackup(manifest, preview)
+	})
+}
+
+// restoreSets returns what a restore compares from a decrypted backup: the
+// local aliases the backup covers, the aliases in the backup and the usage
+// statistics saved with them. The manifest is preferred when there is one
+// this version can read and it was saved with the alias file; otherwise the
+// alias file content is used.
+func (am *AliasManager) restoreSets(content, manifest []byte) (local, backup []Alias, usage map[string]AliasUsage, err error) {
+	if manifest != nil {
+		m, err := parseManifest(manifest)
+		if err == nil {
+			err = m.matches(content)
+		}
+		if err == nil {
+			home, _ := homeDir()
+			sources := aliasSources(am.config.targetShell(), home, am.aliasFile(home), am.config.AliasFiles)
+			return am.aliases, m.aliases(home, sources), m.usage(), nil
+		}
+		fmt.Fprintf(os.Stderr, "Restoring from the alias file: %v\n", err)
+	}
+	backup, err = parseAliasContent(content)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// the alias file only holds the main file; aliases from other files are
+	// not part of the backup
+	return am.mainFileAliases(), backup, nil, nil
+}
+
+// mainFileAliases returns the local aliases defined in the main alias file
+func (am *AliasManager) mainFileAliases() []Alias {
+	home, _ := homeDir()
+	main := am.aliasFile(home)
+	var aliases []Alias
+	for _, a := range am.aliases {
+		if a.Source == "" || a.Source == main {
+			aliases = append(aliases, a)
+		}
+	}
+	return aliases
+}
+
+// showRestorePreview lists the differences between local, the part of the
+// aliases the backup covers, and backup with a checkbox each. Additions and
+// changes are ticked by default; removals have to be ticked explicitly.
+// usage holds statistics from the backup, which are kept for the aliases
+// the restore adds.
+func (am *AliasManager) showRestorePreview(title string, local, backup []Alias, usage map[string]AliasUsage) {
+	changes := diffAliases(local, backup)
+	if len(changes) == 0 {
+		dialog.ShowInformation(title, "Your aliases already match the backup.", am.window)
+		return
+	}
+
+	selected := make([]bool, len(changes))
+	rows := container.NewVBox()
+	counts := map[string]int{}
+	for i, c := range changes {
+		i := i
+		counts[c.Kind]++
+		selected[i] = c.Kind != changeRemoved
+		check := widget.NewCheck(changeText(c), func(on bool) {
+			selected[i] = on
+		})
+		check.SetChecked(selected[i])
+		rows.Add(check)
+	}
+	summary := widget.NewLabel(fmt.Sprintf("%d added, %d changed, %d only local (removed by the backup). Tick the changes to apply.",
+		counts[changeAdded], counts[changeChanged], counts[changeRemoved]))
+	summary.Wrapping = fyne.TextWrapWord
+
+	d := dialog.NewCustomConfirm(title, "Apply", "Cancel",
+		container.NewBorder(summary, nil, nil, nil, container.NewVScroll(rows)), func(ok bool) {
+			if !ok {
+				return
+			}
+			apply, restored := selectedChanges(changes, selected, usage)
+			if home, err := homeDir(); err == nil {
+				if err := saveRestoredUsage(home, restored); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to save restored usage statistics: %v\n", err)
+				}
+			}
+			am.applyRestore(applyAliasChanges(am.aliases, apply))
+		}, am.window)
+	d.Resize(fyne.NewSize(650, 450))
+	d.Show()
+}
+
+// selectedChanges returns the ticked changes and the backup's usage
+// statistics of the aliases they add
+func selectedChanges(changes []AliasChange, selected []bool, usage map[string]AliasUsage) ([]AliasChange, map[string]AliasUsage) {
+	var apply []AliasChange
+	restored := map[string]AliasUsage{}
+	for i, c := range changes {
+		if !selected[i] {
+			continue
+		}
+		apply = append(apply, c)
+		if u, ok := usage[c.Name]; ok && c.Kind == changeAdded {
+			restored[c.Name] = u
+		}
+	}
+	return apply, restored
+}
+
+// applyRestore saves the merged aliases, falling back to a file chooser when
+// the alias file cannot be written due to sandboxing
+func (am *AliasManager) applyRestore(merged []Alias) {
+	previous := am.aliases
+	am.aliases = merged
+	var skipped *skippedAliasesError
+	if err := am.saveAliases(); errors.As(err, &skipped) {
+		// the other aliases were saved
+		dialog.ShowError(err, am.window)
+		if err := am.loadAliases(); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+		am.refreshList()
+		return
+	} else if err != nil {
+		am.aliases = previous
+		if os.IsPermission(err) {
+			home, _ := homeDir()
+			// Ask user to save file via portal
+			fd := dialog.NewFileSave(func(writer fyne.URIWriteCloser, werr error) {
+				if werr != nil || writer == nil {
+					return
+				}
+				defer writer.Close()
+				if _, werr := writer.Write([]byte(renderAliases(merged))); werr != nil {
+					dialog.ShowError(werr, am.window)
+					return
+				}
+				am.aliases = merged
+				am.refreshList()
+			}, am.window)
+			fd.SetFileName(filepath.Base(am.aliasFile(home)))
+			fd.SetFilter(storage.NewExtensionFileFilter([]string{"aliases", "txt", "sh"}))
+			fd.Show()
+			return
+		}
+		dialog.ShowError(err, am.window)
+		return
+	}
+
+	if err := am.loadAliases(); err != nil {
+		dialog.ShowError(err, am.window)
+		return
+	}
+	am.refreshList()
+	dialog.ShowInformation("Restore", "Aliases restored successfully!", am.window)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffAliases(t *testing.T) {
+	ll := Alias{Name: "ll", Command: "ls -l"}
+	gs := Alias{Name: "gs", Command: "git status"}
+	for _, tt := range []struct {
+		name          string
+		local, backup []Alias
+		want          []AliasChange
+	}{
+		{name: "unchanged", local: []Alias{ll, gs}, backup: []Alias{gs, ll}},
+		{name: "both empty"},
+		{
+			name:   "added",
+			local:  []Alias{ll},
+			backup: []Alias{ll, gs},
+			want:   []AliasChange{{Kind: changeAdded, Name: "gs", Backup: gs}},
+		},
+		{
+			name:   "removed",
+			local:  []Alias{ll, gs},
+			backup: []Alias{ll},
+			want:   []AliasChange{{Kind: changeRemoved, Name: "gs", Local: gs}},
+		},
+		{
+			name:   "changed command",
+			local:  []Alias{ll},
+			backup: []Alias{{Name: "ll", Command: "ls -la"}},
+			want:   []AliasChange{{Kind: changeChanged, Name: "ll", Local: ll, Backup: Alias{Name: "ll", Command: "ls -la"}}},
+		},
+		{
+			name:   "changed type",
+			local:  []Alias{{Name: "G", Command: "| grep"}},
+			backup: []Alias{{Name: "G", Command: "| grep", Type: "global"}},
+			want: []AliasChange{{Kind: changeChanged, Name: "G",
+				Local: Alias{Name: "G", Command: "| grep"}, Backup: Alias{Name: "G", Command: "| grep", Type: "global"}}},
+		},
+		{
+			// the source is not part of the comparison
+			name:   "same alias in another file",
+			local:  []Alias{{Name: "ll", Command: "ls -l", Source: "/h/.work_aliases"}},
+			backup: []Alias{ll},
+		},
+		{
+			name:   "local order first, then additions",
+			local:  []Alias{gs, {Name: "x", Command: "exit"}, ll},
+			backup: []Alias{{Name: "new", Command: "true"}, {Name: "ll", Command: "ls -la"}, {Name: "new", Command: "false"}},
+			want: []AliasChange{
+				{Kind: changeRemoved, Name: "gs", Local: gs},
+				{Kind: changeRemoved, Name: "x", Local: Alias{Name: "x", Command: "exit"}},
+				{Kind: changeChanged, Name: "ll", Local: ll, Backup: Alias{Name: "ll", Command: "ls -la"}},
+				{Kind: changeAdded, Name: "new", Backup: Alias{Name: "new", Command: "false"}},
+			},
+		},
+	} {
+		got := diffAliases(tt.local, tt.backup)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplySelectedChanges(t *testing.T) {
+	main, extra := "/h/.bash_aliases", "/h/.work_aliases"
+	local := []Alias{
+		{Name: "ll", Command: "ls -l", Source: main},
+		{Name: "gs", Command: "git status", Source: main},
+		{Name: "deploy", Command: "make deploy", Source: extra},
+		// same name in another file, which a restore of the main file leaves alone
+		{Name: "gs", Command: "git status -s", Source: extra},
+	}
+	backup := []Alias{
+		{Name: "ll", Command: "ls -la"},
+		{Name: "up", Command: "cd .."},
+		{Name: "tf", Command: "terraform"},
+	}
+	changes := diffAliases(local[:2], backup)
+	// ll changed, gs removed, up and tf added
+	if len(changes) != 4 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	usage := map[string]AliasUsage{"ll": {Count: 9}, "up": {Count: 4}, "tf": {Count: 2}}
+
+	for _, tt := range []struct {
+		name     string
+		selected []bool
+		want     []Alias
+		restored map[string]AliasUsage
+	}{
+		{
+			name:     "nothing",
+			selected: []bool{false, false, false, false},
+			want:     local,
+			restored: map[string]AliasUsage{},
+		},
+		{
+			name:     "the defaults: changes and additions",
+			selected: []bool{true, false, true, true},
+			want: []Alias{
+				{Name: "ll", Command: "ls -la", Source: main},
+				local[1], local[2], local[3],
+				{Name: "up", Command: "cd .."},
+				{Name: "tf", Command: "terraform"},
+			},
+			restored: map[string]AliasUsage{"up": {Count: 4}, "tf": {Count: 2}},
+		},
+		{
+			name:     "removal and one addition",
+			selected: []bool{false, true, false, true},
+			want: []Alias{
+				local[0], local[2], local[3],
+				{Name: "tf", Command: "terraform"},
+			},
+			restored: map[string]AliasUsage{"tf": {Count: 2}},
+		},
+	} {
+		apply, restored := selectedChanges(changes, tt.selected, usage)
+		if got := applyAliasChanges(local, apply); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(restored, tt.restored) {
+			t.Errorf("%s: restored usage %+v, want %+v", tt.name, restored, tt.restored)
+		}
+	}
+}
+
+func TestRestoreSets(t *testing.T) {
+	am, home := testManager(t)
+	content := []byte("alias ll='ls -la'\nalias deploy='make deploy'\n")
+	manifest, err := (&AliasManager{
+		config:  am.config,
+		aliases: []Alias{{Name: "ll", Command: "ls -la"}, {Name: "deploy", Command: "make deploy", Source: filepath.Join(home, ".work_aliases")}},
+		usage:   map[string]AliasUsage{"deploy": {Count: 3}},
+	}).buildManifest(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// with a matching manifest every local alias is compared and aliases
+	// return to their own file
+	local, backup, usage, err := am.restoreSets(content, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(local) != len(am.aliases) || usage["deploy"].Count != 3 {
+		t.Errorf("manifest restore: local %+v, usage %+v", local, usage)
+	}
+	if backup[1].Source != filepath.Join(home, ".work_aliases") {
+		t.Errorf("deploy restored to %q", backup[1].Source)
+	}
+
+	// without a manifest, or with one saved for other content, only the main
+	// file is compared
+	for name, m := range map[string][]byte{"no manifest": nil, "other content": manifest} {
+		c := content
+		if m != nil {
+			c = []byte("alias ll='ls -la'\n")
+		}
+		local, backup, usage, err := am.restoreSets(c, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(local) != 2 || local[0].Name != "ll" || local[1].Name != "G" || usage != nil {
+			t.Errorf("%s: local %+v, usage %+v", name, local, usage)
+		}
+		for _, c := range diffAliases(local, backup) {
+			if c.Name == "deploy" && c.Kind == changeRemoved {
+				t.Errorf("%s: alias of an extra file would be removed", name)
+			}
+		}
+	}
+}
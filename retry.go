@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+)
+
+const (
+	// maxRetries is how often a failed request is repeated
+	maxRetries = 3
+	// maxRetryWait is the longest a request waits for a rate limit to reset;
+	// longer limits are reported instead
+	maxRetryWait = time.Minute
+)
+
+// httpClient sends the requests of the backup providers, the GitHub login
+// and the update check, retrying transient failures
+var httpClient = &http.Client{Transport: &retryTransport{base: http.DefaultTransport}}
+
+// retryTransport repeats requests that failed because of the network, a
+// server error or a rate limit. Waits follow Retry-After and
+// X-RateLimit-Reset when the server sends them and back off exponentially
+// otherwise. Requests that may have created something (POST) are only
+// repeated when they cannot have reached the server.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		try := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			try = req.Clone(ctx)
+			try.Body = body
+		}
+		resp, err := t.base.RoundTrip(try)
+		wait, retry := retryDelay(req, resp, err, attempt)
+		if !retry || attempt >= maxRetries || wait > maxRetryWait || (req.Body != nil && req.GetBody == nil) {
+			if err != nil && isUnreachable(err) {
+				err = &unreachableError{host: req.URL.Host, err: err}
+			}
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		reportStatus(ctx, fmt.Sprintf("%s did not answer; retrying in %v…", req.URL.Host, wait.Round(time.Second)))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryDelay decides whether the outcome of a request is worth another
+// attempt and how long to wait before it
+func retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	idempotent := req.Method != "POST"
+	if err != nil {
+		if req.Context().Err() != nil {
+			return 0, false
+		}
+		return backoff(attempt), idempotent || notSent(err)
+	}
+	switch {
+	case resp.StatusCode == 429 || (resp.StatusCode == 403 && isRateLimited(resp.Header)):
+		if wait, ok := rateLimitWait(resp.Header, time.Now()); ok {
+			return wait, true
+		}
+		return backoff(attempt), true
+	case resp.StatusCode == 502 || resp.StatusCode == 503 || resp.StatusCode == 504:
+		return backoff(attempt), idempotent
+	}
+	return 0, false
+}
+
+// retryUnit is the first wait of backoff; tests shorten it
+var retryUnit = time.Second
+
+// backoff returns the wait before retry attempt+1: one second, doubled for
+// every further attempt, with up to a quarter of jitter
+func backoff(attempt int) time.Duration {
+	d := retryUnit << attempt
+	return d + time.Duration(rand.Int63n(int64(d/4)+1))
+}
+
+// isRateLimited reports whether a 403 response is GitHub's primary or
+// secondary rate limit rather than missing permissions
+func isRateLimited(h http.Header) bool {
+	return h.Get("X-RateLimit-Remaining") == "0" || h.Get("Retry-After") != ""
+}
+
+// rateLimitWait returns how long the headers of a rate-limited response ask
+// to wait: Retry-After in seconds, or until X-RateLimit-Reset
+func rateLimitWait(h http.Header, now time.Time) (time.Duration, bool) {
+	if s, err := strconv.Atoi(h.Get("Retry-After")); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := time.Unix(reset, 0).Sub(now) + time.Second
+			if wait < 0 {
+				wait = 0
+			}
+			return wait, true
+		}
+	}
+	return 0, false
+}
+
+// rateLimitReset returns when a rate limit reported by the Gist client or
+// the update check ends
+func rateLimitReset(err error) (time.Time, bool) {
+	var rle *github.RateLimitError
+	if errors.As(err, &rle) {
+		return rle.Rate.Reset.Time, true
+	}
+	var are *github.AbuseRateLimitError
+	if errors.As(err, &are) {
+		if are.RetryAfter != nil {
+			return time.Now().Add(*are.RetryAfter), true
+		}
+		return time.Now().Add(maxRetryWait), true
+	}
+	var er *github.ErrorResponse
+	if errors.As(err, &er) && er.Response != nil && er.Response.StatusCode == 429 {
+		wait, ok := rateLimitWait(er.Response.Header, time.Now())
+		if !ok {
+			wait = maxRetryWait
+		}
+		return time.Now().Add(wait), true
+	}
+	var rse *rateLimitStatusError
+	if errors.As(err, &rse) {
+		return rse.reset, true
+	}
+	return time.Time{}, false
+}
+
+// rateLimitStatusError is a rate-limited response to a plain HTTP request
+type rateLimitStatusError struct {
+	reset time.Time
+}
+
+func (e *rateLimitStatusError) Error() string {
+	return "GitHub API rate limit exceeded; try again after " + e.reset.Local().Format("15:04")
+}
+
+// statusError describes an unexpected response status, explaining rate
+// limits
+func statusError(resp *http.Response) error {
+	if resp.StatusCode == 429 || (resp.StatusCode == 403 && isRateLimited(resp.Header)) {
+		wait, ok := rateLimitWait(resp.Header, time.Now())
+		if !ok {
+			wait = maxRetryWait
+		}
+		return &rateLimitStatusError{reset: time.Now().Add(wait)}
+	}
+	return fmt.Errorf("status %d", resp.StatusCode)
+}
+
+// unreachableError is a request that could not reach its server
+type unreachableError struct {
+	host string
+	err  error
+}
+
+func (e *unreachableError) Error() string {
+	return fmt.Sprintf("%s cannot be reached, you may be offline (%v)", e.host, e.err)
+}
+
+func (e *unreachableError) Unwrap() error {
+	return e.err
+}
+
+// isUnreachable reports whether err means the server could not be reached:
+// the name did not resolve, the connection failed or the network is down
+func isUnreachable(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial") ||
+		errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH)
+}
+
+// notSent reports whether a failed request cannot have reached the server
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// statusKey holds the status function of runInBackground in a context
+type statusKey struct{}
+
+// reportStatus shows message in the progress dialog running ctx, if any
+func reportStatus(ctx context.Context, message string) {
+	if status, ok := ctx.Value(statusKey{}).(func(string)); ok {
+		status(message)
+	}
+}
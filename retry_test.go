@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// shortRetries shortens the backoff for the rest of the test
+func shortRetries(t *testing.T) {
+	unit := retryUnit
+	retryUnit = time.Millisecond
+	t.Cleanup(func() { retryUnit = unit })
+}
+
+// flakyServer answers with the given responses in turn, repeating the last
+// one, and counts the requests
+type flakyServer struct {
+	responses []func(w http.ResponseWriter)
+	mu        sync.Mutex
+	requests  int
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	i := f.requests
+	f.requests++
+	f.mu.Unlock()
+	if i >= len(f.responses) {
+		i = len(f.responses) - 1
+	}
+	f.responses[i](w)
+}
+
+// status answers with code and headers given as name, value pairs
+func status(code int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(code)
+		fmt.Fprint(w, code)
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	shortRetries(t)
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	later := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	for _, tt := range []struct {
+		name      string
+		method    string
+		responses []func(http.ResponseWriter)
+		status    int
+		requests  int
+	}{
+		{name: "success", method: "GET", responses: []func(http.ResponseWriter){status(200)}, status: 200, requests: 1},
+		{name: "server error", method: "GET", responses: []func(http.ResponseWriter){status(503), status(502), status(200)}, status: 200, requests: 3},
+		{name: "gives up", method: "PUT", responses: []func(http.ResponseWriter){status(504)}, status: 504, requests: maxRetries + 1},
+		{name: "POST may have created something", method: "POST", responses: []func(http.ResponseWriter){status(503), status(201)}, status: 503, requests: 1},
+		{name: "not a transient error", method: "GET", responses: []func(http.ResponseWriter){status(500), status(200)}, status: 500, requests: 1},
+		{name: "forbidden", method: "GET", responses: []func(http.ResponseWriter){status(403), status(200)}, status: 403, requests: 1},
+		{name: "Retry-After", method: "GET", responses: []func(http.ResponseWriter){status(429, "Retry-After", "0"), status(200)}, status: 200, requests: 2},
+		// a rate limit is retried even for POST: the request was refused
+		{name: "POST rate-limited", method: "POST", responses: []func(http.ResponseWriter){status(429), status(201)}, status: 201, requests: 2},
+		{
+			name:      "X-RateLimit-Reset passed",
+			method:    "GET",
+			responses: []func(http.ResponseWriter){status(403, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", past), status(200)},
+			status:    200,
+			requests:  2,
+		},
+		{
+			// limits lasting longer than maxRetryWait are reported
+			name:      "X-RateLimit-Reset too late",
+			method:    "GET",
+			responses: []func(http.ResponseWriter){status(403, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", later), status(200)},
+			status:    403,
+			requests:  1,
+		},
+		{name: "Retry-After too late", method: "GET", responses: []func(http.ResponseWriter){status(429, "Retry-After", "3600"), status(200)}, status: 429, requests: 1},
+	} {
+		fake := &flakyServer{responses: tt.responses}
+		srv := httptest.NewServer(fake)
+		req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("body"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := httpClient.Do(req)
+		srv.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || fake.requests != tt.requests {
+			t.Errorf("%s: status %d after %d requests, want %d after %d", tt.name, resp.StatusCode, fake.requests, tt.status, tt.requests)
+		}
+	}
+}
+
+func TestRetryTransportResendsBody(t *testing.T) {
+	shortRetries(t)
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) == 1 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer srv.Close()
+	req, _ := http.NewRequest("PUT", srv.URL, strings.NewReader("alias a='1'"))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 2 || bodies[0] != "alias a='1'" || bodies[1] != bodies[0] {
+		t.Errorf("bodies %q", bodies)
+	}
+}
+
+func TestRetryTransportUnreachable(t *testing.T) {
+	shortRetries(t)
+	// a port nobody listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	var statuses []string
+	ctx := context.WithValue(context.Background(), statusKey{}, func(s string) { statuses = append(statuses, s) })
+	req, _ := http.NewRequestWithContext(ctx, "POST", "http://"+addr+"/gists", strings.NewReader("{}"))
+	_, err = httpClient.Do(req)
+	var ue *unreachableError
+	if !errors.As(err, &ue) || !isTemporary(err) {
+		t.Fatalf("err = %v, want an unreachableError", err)
+	}
+	// a refused connection cannot have created anything, so POST is retried
+	if len(statuses) != maxRetries || !strings.Contains(statuses[0], "retrying") {
+		t.Errorf("statuses %q", statuses)
+	}
+}
+
+func TestRetryTransportCancel(t *testing.T) {
+	srv := httptest.NewServer(&flakyServer{responses: []func(http.ResponseWriter){status(503)}})
+	defer srv.Close()
+	// the first backoff is a second; cancelling ends the wait
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	start := time.Now()
+	if _, err := httpClient.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("returned after %v", d)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, base := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := backoff(attempt); d < base || d > base+base/4 {
+				t.Errorf("backoff(%d) = %v, want %v to %v", attempt, d, base, base+base/4)
+			}
+		}
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	for _, tt := range []struct {
+		headers []string
+		wait    time.Duration
+		ok      bool
+	}{
+		{headers: []string{"Retry-After", "30"}, wait: 30 * time.Second, ok: true},
+		{headers: []string{"Retry-After", "0"}, wait: 0, ok: true},
+		// HTTP dates are not used by GitHub or the forges
+		{headers: []string{"Retry-After", "Wed, 21 Oct 2015 07:28:00 GMT"}},
+		{headers: []string{"Retry-After", "-5"}},
+		{headers: []string{"X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1700000060"}, wait: 61 * time.Second, ok: true},
+		{headers: []string{"X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1699999000"}, wait: 0, ok: true},
+		// the reset of a limit that has requests left does not matter
+		{headers: []string{"X-RateLimit-Remaining", "10", "X-RateLimit-Reset", "1700000060"}},
+		{headers: []string{"X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "soon"}},
+		// Retry-After wins
+		{headers: []string{"Retry-After", "5", "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1700000060"}, wait: 5 * time.Second, ok: true},
+		{},
+	} {
+		h := http.Header{}
+		for i := 0; i+1 < len(tt.headers); i += 2 {
+			h.Set(tt.headers[i], tt.headers[i+1])
+		}
+		if wait, ok := rateLimitWait(h, now); wait != tt.wait || ok != tt.ok {
+			t.Errorf("%q: %v, %v; want %v, %v", tt.headers, wait, ok, tt.wait, tt.ok)
+		}
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	h := http.Header{}
+	h.Set("Retry-After", "120")
+	err := statusError(&http.Response{StatusCode: 429, Header: h})
+	reset, ok := rateLimitReset(err)
+	if !ok || time.Until(reset) < 110*time.Second || !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("429: %v, reset %v", err, reset)
+	}
+	if !isTemporary(err) {
+		t.Error("rate limit not temporary")
+	}
+	err = statusError(&http.Response{StatusCode: 403, Header: http.Header{}})
+	if _, ok := rateLimitReset(err); ok || err.Error() != "status 403" || isTemporary(err) {
+		t.Errorf("403: %v", err)
+	}
+}
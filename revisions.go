@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// maxRevisions limits how many revisions the history lists
+const maxRevisions = 30
+
+// BackupRevision is one saved version of the backup
+type BackupRevision struct {
+	Version string
+	Time    string
+	Content []byte
+	// Manifest is the alias manifest saved with the revision, if any
+	Manifest []byte
+	// Aliases is the number of aliases, or -1 when the revision is encrypted
+	Aliases int
+}
+
+// aliasCount returns the number of aliases in backup content, or -1 for an
+// encrypted backup
+func aliasCount(content []byte) int {
+	if isEncryptedBackup(content) {
+		return -1
+	}
+	aliases, _ := parseAliasContent(content)
+	return len(aliases)
+}
+
+// currentAliasContent returns the alias file as it is on disk, or the
+// rendered aliases for fish
+func (am *AliasManager) currentAliasContent() string {
+	home, _ := homeDir()
+	if am.config.targetShell() == shellFish {
+		return renderAliases(am.aliases)
+	}
+	data, err := os.ReadFile(am.aliasFile(home))
+	if err != nil {
+		return renderAliases(am.aliases)
+	}
+	return string(data)
+}
+
+// showBackupHistory fetches the backup revisions and shows them
+func (am *AliasManager) showBackupHistory() {
+	am.withBackupProvider(func(p BackupProvider) {
+		var revisions []BackupRevision
+		am.runInBackground("History", "Listing the backups in "+p.Name()+"…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+			var err error
+			revisions, err = p.Revisions(ctx, maxRevisions)
+			return err
+		}, func(err error) {
+			if errors.Is(err, errNoBackup) || (err == nil && len(revisions) == 0) {
+				dialog.ShowInformation("History", "No backup found. Please backup first.", am.window)
+				return
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to list backups in %s: %v", p.Name(), err), am.window)
+				return
+			}
+			am.showRevisions(revisions)
+		})
+	})
+}
+
+// showRevisions lists revisions with diff and restore actions
+func (am *AliasManager) showRevisions(revisions []BackupRevision) {
+	list := widget.NewList(
+		func() int {
+			return len(revisions)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil,
+				container.NewHBox(widget.NewButton("Diff", nil), widget.NewButton("Restore", nil)),
+				widget.NewLabel("template"))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			rev := revisions[i]
+			count := fmt.Sprintf("%d aliases", rev.Aliases)
+			if rev.Aliases < 0 {
+				count = "encrypted"
+			}
+			row := o.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s  %s  (%.7s)", rev.Time, count, rev.Version))
+			buttons := row.Objects[1].(*fyne.Container)
+			buttons.Objects[0].(*widget.Button).OnTapped = func() {
+				am.openBackup(rev.Content, func(content []byte) {
+					am.showRevisionDiff(rev, content)
+				})
+			}
+			buttons.Objects[1].(*widget.Button).OnTapped = func() {
+				am.restoreBackup("Restore revision from "+rev.Time, rev.Content, rev.Manifest)
+			}
+		},
+	)
+	d := dialog.NewCustom("Backup history", "Close", list, am.window)
+	d.Resize(fyne.NewSize(600, 400))
+	d.Show()
+}
+
+// showRevisionDiff shows a unified diff from a revision to the current file
+func (am *AliasManager) showRevisionDiff(rev BackupRevision, content []byte) {
+	diff := unifiedDiff(string(content), am.currentAliasContent(), "backup "+rev.Time, "current")
+	if diff == "" {
+		dialog.ShowInformation("Diff", "The revision matches the current alias file.", am.window)
+		return
+	}
+	d := dialog.NewCustom("Diff against "+rev.Time, "Close", container.NewScroll(widget.NewTextGridFromString(diff)), am.window)
+	d.Resize(fyne.NewSize(650, 400))
+	d.Show()
+}
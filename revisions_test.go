@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAliasCount(t *testing.T) {
+	encrypted, err := encryptBackup([]byte("alias a='1'\n"), "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for content, want := range map[string]int{
+		"":                           0,
+		"# no aliases\nexport X=1\n": 0,
+		"alias a='1'\nalias b='2'\n": 2,
+		"alias a='1'\n# alias b='2'\nabbr -a -- e nvim\n": 2,
+		string(encrypted): -1,
+	} {
+		if got := aliasCount([]byte(content)); got != want {
+			t.Errorf("aliasCount(%q) = %d, want %d", content, got, want)
+		}
+	}
+}
+
+func TestCurrentAliasContent(t *testing.T) {
+	am, home := testManager(t)
+	// the alias file as it is on disk, comments included
+	onDisk := "# mine\nalias ll='ls -l'\n"
+	if err := os.WriteFile(am.aliasFile(home), []byte(onDisk), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := am.currentAliasContent(); got != onDisk {
+		t.Errorf("currentAliasContent = %q", got)
+	}
+	am.config.Shell = shellFish
+	if got := am.currentAliasContent(); got != renderAliases(am.aliases) {
+		t.Errorf("fish content = %q", got)
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	am, home := testManager(t)
+	ctx := context.Background()
+	p := &memoryManifestProvider{}
+	versions := []string{
+		"alias ll='ls -l'\nalias old='true'\n",
+		"alias ll='ls -la'\n",
+	}
+	for _, v := range versions {
+		if err := saveBackup(ctx, p, []byte(v), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(am.aliasFile(home), []byte(versions[1]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	am.aliases = []Alias{{Name: "ll", Command: "ls -la", Source: am.aliasFile(home)}}
+
+	revs, err := p.Revisions(ctx, maxRevisions)
+	if err != nil || len(revs) != 2 {
+		t.Fatalf("Revisions = %+v, %v", revs, err)
+	}
+	older := revs[1]
+	diff := unifiedDiff(string(older.Content), am.currentAliasContent(), "backup", "current")
+	for _, line := range []string{"-alias ll='ls -l'", "-alias old='true'", "+alias ll='ls -la'"} {
+		if !strings.Contains(diff, line+"\n") {
+			t.Errorf("diff lacks %q:\n%s", line, diff)
+		}
+	}
+
+	local, backup, usage, err := am.restoreSets(older.Content, older.Manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := diffAliases(local, backup)
+	selected := make([]bool, len(changes))
+	for i, c := range changes {
+		// restore only the alias the newer revision dropped
+		selected[i] = c.Name == "old"
+	}
+	apply, _ := selectedChanges(changes, selected, usage)
+	got := renderAliases(applyAliasChanges(am.aliases, apply))
+	if want := "alias ll='ls -la'\nalias old='true'\n"; got != want {
+		t.Errorf("restored %q, want %q", got, want)
+	}
+}
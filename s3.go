@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	// defaultS3Endpoint and defaultS3Region are used when none are configured
+	defaultS3Endpoint = "https://s3.amazonaws.com"
+	defaultS3Region   = "us-east-1"
+)
+
+// s3Store keeps backup objects in an S3-compatible bucket (AWS, MinIO,
+// Ceph, ...). Requests use path-style URLs and Signature Version 4.
+type s3Store struct {
+	endpoint string
+	region   string
+	bucket   string
+	// prefix is prepended to object keys; empty or ending in a slash
+	prefix    string
+	accessKey string
+	secretKey string
+}
+
+// openS3Provider opens the S3 provider once the secret key is available
+func openS3Provider(am *AliasManager, fn func(BackupProvider)) {
+	if am.config.S3Bucket == "" || am.config.S3AccessKey == "" {
+		dialog.ShowInformation("Backup", "Enter the S3 bucket and access key to back up to in Settings → Backup.", am.window)
+		return
+	}
+	am.withServiceToken(serviceS3, func(secret string) {
+		store := &s3Store{
+			endpoint:  forgeURL(am.config.S3Endpoint, defaultS3Endpoint),
+			region:    am.config.S3Region,
+			bucket:    am.config.S3Bucket,
+			prefix:    strings.Trim(am.config.S3Prefix, "/"),
+			accessKey: am.config.S3AccessKey,
+			secretKey: secret,
+		}
+		if store.region == "" {
+			store.region = defaultS3Region
+		}
+		if store.prefix != "" {
+			store.prefix += "/"
+		}
+		fn(&objectProvider{name: "S3 bucket " + store.bucket, store: store})
+	})
+}
+
+// awsEscape percent-encodes s as required by Signature Version 4, leaving
+// slashes alone when keepSlash is set
+func awsEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', keepSlash && c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// sigV4Signature signs a canonical request and returns the credential scope
+// and signature
+func sigV4Signature(secret, region, service string, now time.Time, canonical string) (string, string) {
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	day := now.UTC().Format("20060102")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + now.UTC().Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := mac([]byte("AWS4"+secret), day)
+	key = mac(key, region)
+	key = mac(key, service)
+	key = mac(key, "aws4_request")
+	return scope, hex.EncodeToString(mac(key, toSign))
+}
+
+// sigV4Canonical returns the canonical request of a request signing the
+// host, x-amz-content-sha256 and x-amz-date headers
+func sigV4Canonical(method, path, rawQuery, host, payloadHash, amzDate string) string {
+	return strings.Join([]string{
+		method,
+		path,
+		rawQuery,
+		"host:" + host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		sigV4SignedHeaders,
+		payloadHash,
+	}, "\n")
+}
+
+// sigV4SignedHeaders lists the headers signed by sigV4Canonical
+const sigV4SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+
+// request sends a signed request for key, or for the bucket itself when key
+// is empty. An endpoint with a path, such as a MinIO behind a reverse proxy
+// at https://host/minio, keeps it in front of the bucket.
+func (s *s3Store) request(ctx context.Context, method, key string, query map[string]string, body []byte) ([]byte, error) {
+	endpoint, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %v", s.endpoint, err)
+	}
+	path := awsEscape(strings.TrimRight(endpoint.Path, "/"), true) + "/" + awsEscape(s.bucket, false)
+	if key != "" {
+		path += "/" + awsEscape(key, true)
+	}
+	var params []string
+	for k, v := range query {
+		params = append(params, awsEscape(k, false)+"="+awsEscape(v, false))
+	}
+	sort.Strings(params)
+	rawQuery := strings.Join(params, "&")
+	target := endpoint.Scheme + "://" + endpoint.Host + path
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	canonical := sigV4Canonical(method, path, rawQuery, req.URL.Host, payloadHash, amzDate)
+	scope, signature := sigV4Signature(s.secretKey, s.region, "s3", now, canonical)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, sigV4SignedHeaders, signature))
+
+	data, err := objectRequest(req)
+	if isStatus(err, 403) {
+		return nil, fmt.Errorf("access denied; check the access key, secret key and region: %v", err)
+	}
+	return data, err
+}
+
+func (s *s3Store) put(ctx context.Context, name string, data []byte) error {
+	_, err := s.request(ctx, "PUT", s.prefix+name, nil, data)
+	return err
+}
+
+func (s *s3Store) get(ctx context.Context, name string) ([]byte, error) {
+	data, err := s.request(ctx, "GET", s.prefix+name, nil, nil)
+	if isStatus(err, 404) {
+		return nil, errNoBackup
+	}
+	return data, err
+}
+
+// s3ListResult is the part of a ListObjectsV2 response used here
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3Store) list(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	query := map[string]string{"list-type": "2", "prefix": s.prefix + prefix}
+	for {
+		data, err := s.request(ctx, "GET", "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		if err := xml.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("invalid S3 response: %v", err)
+		}
+		for _, c := range result.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, s.prefix))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		query["continuation-token"] = result.NextContinuationToken
+	}
+}
+
+// s3Settings configures the endpoint, bucket and access key. Changing the
+// endpoint or access key forgets the stored secret key.
+func s3Settings(am *AliasManager) fyne.CanvasObject {
+	endpointEntry := widget.NewEntry()
+	endpointEntry.SetPlaceHolder(defaultS3Endpoint)
+	endpointEntry.SetText(am.config.S3Endpoint)
+	regionEntry := widget.NewEntry()
+	regionEntry.SetPlaceHolder(defaultS3Region)
+	regionEntry.SetText(am.config.S3Region)
+	bucketEntry := widget.NewEntry()
+	bucketEntry.SetText(am.config.S3Bucket)
+	prefixEntry := widget.NewEntry()
+	prefixEntry.SetPlaceHolder("optional, e.g. aliases/")
+	prefixEntry.SetText(am.config.S3Prefix)
+	keyEntry := widget.NewEntry()
+	keyEntry.SetText(am.config.S3AccessKey)
+
+	applyBtn := widget.NewButton("Apply", func() {
+		endpoint := strings.TrimSpace(endpointEntry.Text)
+		key := strings.TrimSpace(keyEntry.Text)
+		if forgeURL(endpoint, defaultS3Endpoint) != forgeURL(am.config.S3Endpoint, defaultS3Endpoint) || key != am.config.S3AccessKey {
+			if err := am.forgetToken(serviceS3); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to remove the S3 secret key: %v", err), am.window)
+			}
+		}
+		am.config.S3Endpoint = endpoint
+		am.config.S3Region = strings.TrimSpace(regionEntry.Text)
+		am.config.S3Bucket = strings.TrimSpace(bucketEntry.Text)
+		am.config.S3Prefix = strings.TrimSpace(prefixEntry.Text)
+		am.config.S3AccessKey = key
+		if err := am.saveConfig(); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+	})
+	form := widget.NewForm(
+		widget.NewFormItem("Endpoint", endpointEntry),
+		widget.NewFormItem("Region", regionEntry),
+		widget.NewFormItem("Bucket", bucketEntry),
+		widget.NewFormItem("Key prefix", prefixEntry),
+		widget.NewFormItem("Access key ID", keyEntry),
+	)
+	return container.NewVBox(form, container.NewHBox(applyBtn))
+}
@@ -20,6 +20,11 @@ else
     echo "Using provided VERSION=$VERSION"
 fi
 
+# GITHUB_OAUTH_CLIENT_ID enables "Sign in with browser" (OAuth device flow)
+if [ -z "${GITHUB_OAUTH_CLIENT_ID:-}" ]; then
+    echo "Note: GITHUB_OAUTH_CLIENT_ID is not set; the build will ask for a personal access token instead of signing in through the browser"
+fi
+
 echo "Building release artifacts for $NAME version $VERSION"
 
 rm -rf "$DIST_DIR"
@@ -69,7 +74,7 @@ for t in "${TARGETS[@]}"; do
         CGO=0
     fi
 
-    if env CGO_ENABLED=$CGO GOOS=$GOOS GOARCH=$GOARCH go build -ldflags "-s -w -X main.Version=$VERSION" -o "$OUTDIR/$BINNAME" ./...; then
+    if env CGO_ENABLED=$CGO GOOS=$GOOS GOARCH=$GOARCH go build -ldflags "-s -w -X main.Version=$VERSION -X main.OAuthClientID=${GITHUB_OAUTH_CLIENT_ID:-}" -o "$OUTDIR/$BINNAME" ./...; then
         echo "- Built $OUTDIR/$BINNAME"
     else
         echo "Error: 'go build' failed for $GOOS/$GOARCH. The GUI (Fyne) may require native libraries or CGO;" >&2
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Access tokens are not written to the config file. They are kept in the
+// Secret Service keyring (GNOME Keyring, KWallet) when one is running on the
+// session bus, and otherwise in a file encrypted with a passphrase.
+// Config.TokenStorage (GitHub) and Config.TokenStores (other services) record
+// which of the two holds each token.
+
+const (
+	tokenStorageKeyring = "keyring"
+	tokenStorageFile    = "file"
+
+	secretServiceName = "org.freedesktop.secrets"
+	secretServicePath = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretPromptWait  = 2 * time.Minute
+	// keyringTimeout limits a keyring operation including an unlock prompt
+	keyringTimeout = secretPromptWait + 30*time.Second
+)
+
+// errWrongPassphrase is returned when an encrypted file cannot be opened
+var errWrongPassphrase = errors.New("wrong passphrase or damaged file")
+
+// secretAttributes identify the token item of a service in the keyring
+func secretAttributes(service string) map[string]string {
+	return map[string]string{
+		"application": appDirName,
+		"service":     service,
+	}
+}
+
+// secretValue is the Secret Service (oayays) secret struct
+type secretValue struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// secretSession is an open connection to the Secret Service
+type secretSession struct {
+	conn    *dbus.Conn
+	service dbus.BusObject
+	path    dbus.ObjectPath
+}
+
+// openSecretSession connects to the Secret Service. The "plain" algorithm is
+// used as the secret only travels over the local session bus.
+func openSecretSession() (*secretSession, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	s := &secretSession{conn: conn, service: conn.Object(secretServiceName, secretServicePath)}
+	var output dbus.Variant
+	if err := s.service.Call("org.freedesktop.Secret.Service.OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &s.path); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *secretSession) Close() {
+	s.conn.Object(secretServiceName, s.path).Call("org.freedesktop.Secret.Session.Close", 0)
+	s.conn.Close()
+}
+
+// prompt runs a Secret Service prompt, such as the unlock dialog of a locked
+// keyring, and waits for the user to complete it
+func (s *secretSession) prompt(path dbus.ObjectPath) (dbus.Variant, error) {
+	if path == "/" {
+		return dbus.Variant{}, nil
+	}
+	ch := make(chan *dbus.Signal, 4)
+	s.conn.Signal(ch)
+	defer s.conn.RemoveSignal(ch)
+	if err := s.conn.AddMatchSignal(dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface("org.freedesktop.Secret.Prompt"), dbus.WithMatchMember("Completed")); err != nil {
+		return dbus.Variant{}, err
+	}
+	if err := s.conn.Object(secretServiceName, path).Call("org.freedesktop.Secret.Prompt.Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, err
+	}
+	timeout := time.After(secretPromptWait)
+	for {
+		select {
+		case sig := <-ch:
+			if sig.Path != path || sig.Name != "org.freedesktop.Secret.Prompt.Completed" || len(sig.Body) < 2 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return dbus.Variant{}, fmt.Errorf("keyring prompt was dismissed")
+			}
+			result, _ := sig.Body[1].(dbus.Variant)
+			return result, nil
+		case <-timeout:
+			return dbus.Variant{}, fmt.Errorf("timed out waiting for the keyring prompt")
+		}
+	}
+}
+
+// findItems returns the unlocked keyring items holding the token of
+// service, unlocking them first when needed
+func (s *secretSession) findItems(service string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := s.service.Call("org.freedesktop.Secret.Service.SearchItems", 0, secretAttributes(service)).Store(&unlocked, &locked); err != nil {
+		return nil, err
+	}
+	if len(locked) == 0 {
+		return unlocked, nil
+	}
+	var done []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := s.service.Call("org.freedesktop.Secret.Service.Unlock", 0, locked).Store(&done, &prompt); err != nil {
+		return nil, err
+	}
+	result, err := s.prompt(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if paths, ok := result.Value().([]dbus.ObjectPath); ok {
+		done = append(done, paths...)
+	}
+	return append(unlocked, done...), nil
+}
+
+// keyringGet returns the token of service from the keyring, or "" when none
+// is stored
+func keyringGet(service string) (string, error) {
+	s, err := openSecretSession()
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+	items, err := s.findItems(service)
+	if err != nil || len(items) == 0 {
+		return "", err
+	}
+	var secret secretValue
+	if err := s.conn.Object(secretServiceName, items[0]).Call("org.freedesktop.Secret.Item.GetSecret", 0, s.path).Store(&secret); err != nil {
+		return "", err
+	}
+	return string(secret.Value), nil
+}
+
+// keyringSet stores the token of service in the default keyring collection,
+// replacing an earlier one
+func keyringSet(service, token string) error {
+	s, err := openSecretSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	var collection dbus.ObjectPath
+	if err := s.service.Call("org.freedesktop.Secret.Service.ReadAlias", 0, "default").Store(&collection); err != nil {
+		return err
+	}
+	if collection == "/" {
+		return fmt.Errorf("no default keyring")
+	}
+	props := map[string]dbus.Variant{
+		"org.freedesktop.Secret.Item.Label":      dbus.MakeVariant("Bash Alias Manager " + serviceLabel(service) + " token"),
+		"org.freedesktop.Secret.Item.Attributes": dbus.MakeVariant(secretAttributes(service)),
+	}
+	secret := secretValue{Session: s.path, Value: []byte(token), ContentType: "text/plain"}
+	var item, prompt dbus.ObjectPath
+	if err := s.conn.Object(secretServiceName, collection).Call("org.freedesktop.Secret.Collection.CreateItem", 0, props, secret, true).Store(&item, &prompt); err != nil {
+		return err
+	}
+	_, err = s.prompt(prompt)
+	return err
+}
+
+// keyringDelete removes the token of service from the keyring
+func keyringDelete(service string) error {
+	s, err := openSecretSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	items, err := s.findItems(service)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		var prompt dbus.ObjectPath
+		if err := s.conn.Object(secretServiceName, item).Call("org.freedesktop.Secret.Item.Delete", 0).Store(&prompt); err != nil {
+			return err
+		}
+		if _, err := s.prompt(prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealedData is the format of data encrypted with a passphrase: AES-256-GCM
+// with a key derived by scrypt
+type sealedData struct {
+	KDF   string `json:"kdf"`
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// scrypt parameters written by sealWithPassphrase. openWithPassphrase
+// accepts only these: the sealed data may come from a downloaded backup, and
+// a crafted one with larger values would make scrypt use gigabytes of memory
+// before the passphrase is checked.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// passphraseAEAD derives the cipher for a passphrase and salt
+func passphraseAEAD(passphrase string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealWithPassphrase encrypts plaintext with a key derived from passphrase
+func sealWithPassphrase(plaintext []byte, passphrase string) ([]byte, error) {
+	s := sealedData{KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 16)}
+	if _, err := rand.Read(s.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := passphraseAEAD(passphrase, s.Salt, s.N, s.R, s.P)
+	if err != nil {
+		return nil, err
+	}
+	s.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(s.Nonce); err != nil {
+		return nil, err
+	}
+	s.Data = aead.Seal(nil, s.Nonce, plaintext, nil)
+	return json.Marshal(s)
+}
+
+// openWithPassphrase decrypts data written by sealWithPassphrase
+func openWithPassphrase(data []byte, passphrase string) ([]byte, error) {
+	var s sealedData
+	if err := json.Unmarshal(data, &s); err != nil || s.KDF != "scrypt" {
+		return nil, fmt.Errorf("unrecognised encrypted data")
+	}
+	if s.N != scryptN || s.R != scryptR || s.P != scryptP {
+		return nil, fmt.Errorf("unsupported encryption parameters (scrypt N=%d, r=%d, p=%d)", s.N, s.R, s.P)
+	}
+	aead, err := passphraseAEAD(passphrase, s.Salt, s.N, s.R, s.P)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("unrecognised encrypted data")
+	}
+	plaintext, err := aead.Open(nil, s.Nonce, s.Data, nil)
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// tokenFilePath returns the passphrase-encrypted token file of service
+func tokenFilePath(home, service string) string {
+	return filepath.Join(configDir(home), service+"_token.enc")
+}
+
+// writeTokenFile encrypts the token into the token file, readable by the
+// owner only
+func writeTokenFile(home, service, token, passphrase string) error {
+	data, err := sealWithPassphrase([]byte(token), passphrase)
+	if err != nil {
+		return err
+	}
+	path := tokenFilePath(home, service)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return err
+	}
+	// writeFileAtomic keeps the mode of an existing file, which may be too open
+	return os.Chmod(path, 0600)
+}
+
+// readTokenFile decrypts the token file of service
+func readTokenFile(home, service, passphrase string) (string, error) {
+	data, err := os.ReadFile(tokenFilePath(home, service))
+	if err != nil {
+		return "", err
+	}
+	token, err := openWithPassphrase(data, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
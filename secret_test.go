@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSealWithPassphrase(t *testing.T) {
+	sealed, err := sealWithPassphrase([]byte("gho_secret"), "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := openWithPassphrase(sealed, "correct horse")
+	if err != nil || string(plain) != "gho_secret" {
+		t.Fatalf("open = %q, %v", plain, err)
+	}
+	if _, err := openWithPassphrase(sealed, "wrong"); !errors.Is(err, errWrongPassphrase) {
+		t.Errorf("wrong passphrase: %v", err)
+	}
+	if _, err := openWithPassphrase([]byte(`{"kdf":"argon2id"}`), "x"); err == nil {
+		t.Error("unknown KDF accepted")
+	}
+}
+
+func TestOpenWithPassphraseRejectsCostParameters(t *testing.T) {
+	sealed, err := sealWithPassphrase([]byte("gho_secret"), "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		n, r, p int
+	}{
+		// each of these would need gigabytes of memory or minutes of CPU
+		{"huge N", 1 << 30, scryptR, scryptP},
+		{"huge r", scryptN, 1 << 16, scryptP},
+		{"huge p", scryptN, scryptR, 1 << 20},
+		{"weaker N", 1 << 10, scryptR, scryptP},
+	} {
+		var s sealedData
+		if err := json.Unmarshal(sealed, &s); err != nil {
+			t.Fatal(err)
+		}
+		s.N, s.R, s.P = tt.n, tt.r, tt.p
+		crafted, _ := json.Marshal(s)
+		if _, err := openWithPassphrase(crafted, "pw"); err == nil || errors.Is(err, errWrongPassphrase) {
+			t.Errorf("%s: err = %v, want the parameters refused", tt.name, err)
+		}
+	}
+}
+
+func TestTokenFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	if err := writeTokenFile(home, "test-service", "gho_secret", "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(tokenFilePath(home, "test-service")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("token file mode %v, %v", fi.Mode(), err)
+	}
+	if token, err := readTokenFile(home, "test-service", "pw"); err != nil || token != "gho_secret" {
+		t.Errorf("readTokenFile = %q, %v", token, err)
+	}
+}
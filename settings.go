@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showSettings opens the settings dialog
+func (am *AliasManager) showSettings() {
+	integration, refreshIntegration := am.integrationSettings()
+	tabs := container.NewAppTabs(
+		container.NewTabItem("General", am.generalSettings(refreshIntegration)),
+		container.NewTabItem("Shell integration", integration),
+		container.NewTabItem("Backup", am.backupSettings()),
+	)
+	d := dialog.NewCustom("Settings", "Close", tabs, am.window)
+	d.Resize(fyne.NewSize(700, 450))
+	d.Show()
+}
+
+// generalSettings builds the page with the target shell selection. changed
+// is called after the shell was switched.
+func (am *AliasManager) generalSettings(changed func()) fyne.CanvasObject {
+	shellSelect := widget.NewSelect([]string{shellBash, shellZsh, shellFish}, nil)
+	shellSelect.SetSelected(am.config.targetShell())
+	shellSelect.OnChanged = func(shell string) {
+		if shell == am.config.targetShell() {
+			return
+		}
+		if err := am.setTargetShell(shell); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+		changed()
+	}
+
+	home, _ := homeDir()
+	fileEntry := widget.NewEntry()
+	fileEntry.SetPlaceHolder(displayPath(defaultAliasFile(am.config.targetShell(), home), home))
+	fileEntry.SetText(am.config.AliasFile)
+	fileBtn := widget.NewButton("Apply", func() {
+		if err := am.setAliasFile(strings.TrimSpace(fileEntry.Text)); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to change alias file: %v", err), am.window)
+		}
+	})
+	fileItem := widget.NewFormItem("Alias file", container.NewBorder(nil, nil, nil, fileBtn, fileEntry))
+	if aliasFileOverride != "" {
+		fileEntry.SetText(aliasFileOverride)
+		fileEntry.Disable()
+		fileBtn.Disable()
+		fileItem.HintText = "Set by --file for this run"
+	} else {
+		fileItem.HintText = "Leave empty for the default; may point into a dotfiles repository"
+	}
+
+	filesEntry := widget.NewMultiLineEntry()
+	filesEntry.SetPlaceHolder("~/dotfiles/aliases.sh")
+	filesEntry.SetText(strings.Join(am.config.AliasFiles, "\n"))
+	filesBtn := widget.NewButton("Apply", func() {
+		var files []string
+		for _, line := range strings.Split(filesEntry.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				files = append(files, line)
+			}
+		}
+		if err := am.setAliasFiles(files); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to update alias files: %v", err), am.window)
+		}
+	})
+
+	fragmentEntry := widget.NewEntry()
+	fragmentEntry.SetPlaceHolder("git.sh")
+	fragmentBtn := widget.NewButton("Create", func() {
+		if err := am.addFragment(fragmentEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to create alias file: %v", err), am.window)
+			return
+		}
+		fragmentEntry.SetText("")
+	})
+
+	return widget.NewForm(
+		widget.NewFormItem("Target shell", shellSelect),
+		fileItem,
+		widget.NewFormItem("Extra alias files", container.NewBorder(nil, filesBtn, nil, nil, filesEntry)),
+		widget.NewFormItem("New drop-in file", container.NewBorder(nil, nil, nil, fragmentBtn, fragmentEntry)),
+	)
+}
+
+// setAliasFile changes the main alias file; an empty path restores the
+// default for the target shell
+func (am *AliasManager) setAliasFile(path string) error {
+	am.config.AliasFile = path
+	if err := am.saveConfig(); err != nil {
+		return err
+	}
+	am.reloadAliases()
+	return am.ensureShellIntegration()
+}
+
+// setAliasFiles replaces the extra alias files, one path per entry, and
+// reloads the aliases from the new set of files
+func (am *AliasManager) setAliasFiles(files []string) error {
+	am.config.AliasFiles = files
+	if err := am.saveConfig(); err != nil {
+		return err
+	}
+	am.reloadAliases()
+	return am.ensureShellIntegration()
+}
+
+// addFragment creates an empty file in the drop-in directory so aliases can
+// be moved into it
+func (am *AliasManager) addFragment(name string) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	shell := am.config.targetShell()
+	if shell == shellFish {
+		return fmt.Errorf("fish keeps one file per alias; drop-in files are not used")
+	}
+	if _, err := createFragment(shell, home, name); err != nil {
+		return err
+	}
+	return writeLoader(shell, home, am.config)
+}
+
+// setTargetShell switches the shell whose alias file and startup files are
+// managed, then reloads the aliases from the new file
+func (am *AliasManager) setTargetShell(shell string) error {
+	am.config.Shell = shell
+	if err := am.saveConfig(); err != nil {
+		return err
+	}
+	am.selectedIndex = -1
+	am.list.UnselectAll()
+	if err := am.loadAliases(); err != nil {
+		return err
+	}
+	if err := am.reloadUsage(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read usage data: %v\n", err)
+	}
+	am.refreshList()
+	return am.ensureShellIntegration()
+}
+
+// integrationSettings builds the page showing the managed block status of
+// each startup file, with install, repair and remove actions. The returned
+// function refreshes the page.
+func (am *AliasManager) integrationSettings() (fyne.CanvasObject, func()) {
+	home, err := homeDir()
+	if err != nil {
+		return widget.NewLabel(fmt.Sprintf("Cannot determine home directory: %v", err)), func() {}
+	}
+
+	rows := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		rows.Objects = nil
+		shell := am.config.targetShell()
+		for _, path := range integrationFiles(shell, home) {
+			path := path
+			st, err := integrationStatus(shell, path, home)
+			state := st.State
+			if err != nil {
+				state = err.Error()
+			} else {
+				if !st.Exists {
+					state += " (file missing)"
+				}
+				if st.Required && st.State != integrationInstalled {
+					state += ", needed"
+				}
+				state += "\nRead by: " + st.ReadBy
+			}
+			actions := container.NewHBox()
+			for _, action := range []string{"install", "repair", "remove"} {
+				action := action
+				btn := widget.NewButton(actionLabel(action), func() {
+					am.previewIntegration(shell, path, home, action, refresh)
+				})
+				actions.Add(btn)
+			}
+			rows.Add(container.NewBorder(nil, nil,
+				widget.NewLabel(filepath.Base(path)), actions, widget.NewLabel(state)))
+		}
+		if len(rows.Objects) == 0 {
+			rows.Add(widget.NewLabel(shell + " loads the managed aliases automatically; no startup file changes are needed."))
+		}
+		rows.Refresh()
+	}
+	refresh()
+
+	help := widget.NewLabel("Aliases are loaded by a managed block delimited by\n" +
+		managedBlockStart + " and " + managedBlockEnd + ".")
+	return container.NewBorder(help, nil, nil, nil, container.NewVScroll(rows)), refresh
+}
+
+// actionLabel returns the button text for an integration action
+func actionLabel(action string) string {
+	switch action {
+	case "install":
+		return "Install"
+	case "repair":
+		return "Repair"
+	default:
+		return "Remove"
+	}
+}
+
+// previewIntegration shows the diff an integration action would make and
+// applies it after confirmation.
+func (am *AliasManager) previewIntegration(shell, path, home, action string, done func()) {
+	before, after, err := planIntegration(shell, path, home, action)
+	var unterminated *unterminatedBlockError
+	if errors.As(err, &unterminated) {
+		msg := widget.NewLabel(fmt.Sprintf("The managed block in %s has no end marker (%s), so where it ends cannot be told apart from your own code. Remove the block by hand; it starts with:", path, managedBlockEnd))
+		msg.Wrapping = fyne.TextWrapWord
+		d := dialog.NewCustom("Damaged shell integration", "Close", container.NewBorder(msg, nil, nil, nil,
+			container.NewScroll(widget.NewTextGridFromString(unterminated.Block))), am.window)
+		d.Resize(fyne.NewSize(650, 400))
+		d.Show()
+		return
+	}
+	if err != nil {
+		dialog.ShowError(err, am.window)
+		return
+	}
+	if before == after {
+		dialog.ShowInformation("Shell integration", fmt.Sprintf("%s needs no changes.", path), am.window)
+		return
+	}
+	grid := widget.NewTextGridFromString(unifiedDiff(before, after, path, path))
+	d := dialog.NewCustomConfirm(fmt.Sprintf("%s %s", actionLabel(action), filepath.Base(path)), "Apply", "Cancel",
+		container.NewScroll(grid), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := applyIntegration(path, before, after); err != nil {
+				dialog.ShowError(err, am.window)
+			}
+			done()
+		}, am.window)
+	d.Resize(fyne.NewSize(650, 400))
+	d.Show()
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	managedBlockStart = "# >>> bash-alias-manager >>>"
+	managedBlockEnd   = "# <<< bash-alias-manager <<<"
+)
+
+// Supported target shells
+const (
+	shellBash = "bash"
+	shellZsh  = "zsh"
+)
+
+// aliasFileOverride and configFileOverride are set by the --file and
+// --config flags and replace the configured paths for this run only
+var (
+	aliasFileOverride  string
+	configFileOverride string
+)
+
+// homeDir returns the directory the managed dotfiles live in: $BAM_HOME when
+// set, the real home directory under snap confinement, otherwise the user's
+// home directory
+func homeDir() (string, error) {
+	if home := os.Getenv("BAM_HOME"); home != "" {
+		return filepath.Abs(home)
+	}
+	if home := os.Getenv("SNAP_REAL_HOME"); home != "" {
+		return home, nil
+	}
+	return os.UserHomeDir()
+}
+
+// targetShell returns the configured target shell, defaulting to bash
+func (c Config) targetShell() string {
+	if c.Shell == "" {
+		return shellBash
+	}
+	return c.Shell
+}
+
+// aliasFileName returns the alias file of bash or zsh, relative to the home
+// directory
+func aliasFileName(shell string) string {
+	if shell == shellZsh {
+		return ".zsh_aliases"
+	}
+	return ".bash_aliases"
+}
+
+// defaultAliasFile returns the default main alias file of a shell. fish
+// keeps aliases as functions in its config directory instead.
+func defaultAliasFile(shell, home string) string {
+	if shell == shellFish {
+		return filepath.Join(fishConfigDir(home), "functions")
+	}
+	return home + "/" + aliasFileName(shell)
+}
+
+// aliasFilePath returns the configured main alias file of a bash or zsh
+// target, ignoring the --file flag
+func aliasFilePath(shell, home string, config Config) string {
+	if config.AliasFile != "" {
+		return expandPath(config.AliasFile, home)
+	}
+	return defaultAliasFile(shell, home)
+}
+
+// aliasFile returns the main alias file managed in this run
+func (am *AliasManager) aliasFile(home string) string {
+	if aliasFileOverride != "" {
+		return expandPath(aliasFileOverride, home)
+	}
+	return aliasFilePath(am.config.targetShell(), home, am.config)
+}
+
+// zshDir returns the directory zsh reads its startup files from
+func zshDir(home string) string {
+	if dir := os.Getenv("ZDOTDIR"); dir != "" {
+		return dir
+	}
+	return home
+}
+
+// legacySnippets are the unmarked snippets older versions appended to
+// .bashrc. They are replaced by the managed block when it is installed.
+var legacySnippets = []string{
+	"\n# Source bash aliases\nif [ -f ~/.bash_aliases ]; then\n    . ~/.bash_aliases\nfi\n",
+	"\n# Bash Alias Manager usage logging\nif [ -f ~/" + usageHookFile + " ]; then\n    . ~/" + usageHookFile + "\nfi\n",
+}
+
+// initScript returns the shell code that loads the managed aliases. It is
+// printed by the "init" subcommand and embedded in the managed rc block.
+func initScript(shell, home string) (string, error) {
+	if shell == shellFish {
+		return "", fmt.Errorf("fish loads the managed functions and abbreviations automatically; no init code is needed")
+	}
+	if shell != shellBash && shell != shellZsh {
+		return "", fmt.Errorf("unsupported shell %q", shell)
+	}
+	aliases := home + "/" + aliasFileName(shell)
+	loader := home + "/" + loaderName(shell)
+	hook := home + "/" + usageHookName(shell)
+	var b strings.Builder
+	for _, f := range []string{aliases, loader, hook} {
+		fmt.Fprintf(&b, "if [ -f %s ]; then\n    . %s\nfi\n", shellQuote(f), shellQuote(f))
+	}
+	return b.String(), nil
+}
+
+// managedBlock wraps body in the start/end markers so it can be found,
+// updated and removed again later.
+func managedBlock(body string) string {
+	return managedBlockStart + "\n" +
+		"# Added by Bash Alias Manager. Do not edit by hand; changes inside this block are overwritten.\n" +
+		body +
+		managedBlockEnd + "\n"
+}
+
+// findManagedBlock returns the byte range of the managed block in content,
+// including the trailing newline of the end marker.
+func findManagedBlock(content string) (start, end int, ok bool) {
+	start = strings.Index(content, managedBlockStart+"\n")
+	if start < 0 {
+		return 0, 0, false
+	}
+	rel := strings.Index(content[start:], managedBlockEnd)
+	if rel < 0 {
+		return 0, 0, false
+	}
+	end = start + rel + len(managedBlockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return start, end, true
+}
+
+// upsertManagedBlock replaces an existing managed block in content or
+// appends one, removing any legacy unmarked snippets.
+func upsertManagedBlock(content, block string) string {
+	for _, s := range legacySnippets {
+		content = strings.Replace(content, s, "\n", 1)
+	}
+	if start, end, ok := findManagedBlock(content); ok {
+		return content[:start] + block + content[end:]
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if content != "" {
+		content += "\n"
+	}
+	return content + block
+}
+
+// removeManagedBlock strips every managed block and legacy snippet from
+// content, and end markers left over from hand edits. A start marker without
+// an end marker is an error: where such a block ends cannot be told apart
+// from the user's own code, so it is left for the user to remove.
+func removeManagedBlock(content string) (string, error) {
+	for _, s := range legacySnippets {
+		content = strings.Replace(content, s, "\n", 1)
+	}
+	for {
+		start, end, ok := findManagedBlock(content)
+		if !ok {
+			break
+		}
+		if strings.Contains(content[start+len(managedBlockStart):end], managedBlockStart) {
+			// the first start marker belongs to a block without an end
+			break
+		}
+		before := content[:start]
+		// drop the blank separator line that upsertManagedBlock added
+		if strings.HasSuffix(before, "\n\n") {
+			before = before[:len(before)-1]
+		}
+		content = before + content[end:]
+	}
+	if start := strings.Index(content, managedBlockStart); start >= 0 {
+		return "", &unterminatedBlockError{Block: unterminatedBlock(content[start:])}
+	}
+	if !strings.Contains(content, managedBlockEnd) {
+		return content, nil
+	}
+	var out []string
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if strings.TrimSpace(line) != managedBlockEnd {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, ""), nil
+}
+
+// unterminatedBlockError reports a managed block whose end marker is missing
+type unterminatedBlockError struct {
+	// Block is the start of the block as found in the file
+	Block string
+}
+
+func (e *unterminatedBlockError) Error() string {
+	return fmt.Sprintf("the managed block has no end marker (%s), so it is not changed automatically. Remove the block by hand; it starts with:\n\n%s", managedBlockEnd, e.Block)
+}
+
+// unterminatedBlock returns the first lines of content, which starts at a
+// start marker, to show where the damaged block is
+func unterminatedBlock(content string) string {
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) > 12 {
+		lines = append(lines[:12], "...\n")
+	}
+	return strings.Join(lines, "")
+}
+
+// writeFileAtomic replaces path with data via a temporary file in the same
+// directory, keeping the existing file mode when there is one. When path is
+// a symlink, as with stow or other dotfile managers, the file it points to
+// is replaced and the link kept.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	path = resolveSymlinks(path)
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// resolveSymlinks follows path to the file it points to, also when that
+// file does not exist yet
+func resolveSymlinks(path string) string {
+	// the limit stops symlink loops
+	for i := 0; i < 40; i++ {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return path
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return path
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileAtomicKeepsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "bashrc")
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("old\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, ".bashrc")
+	if err := os.Symlink("dotfiles/bashrc", link); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(link, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link replaced: %v %v", fi, err)
+	}
+	data, _ := os.ReadFile(target)
+	if string(data) != "new\n" {
+		t.Fatalf("target has %q", data)
+	}
+	if fi, _ := os.Stat(target); fi.Mode().Perm() != 0640 {
+		t.Fatalf("mode changed to %v", fi.Mode())
+	}
+
+	// a dangling link is written through as well
+	dangling := filepath.Join(dir, ".zshrc")
+	if err := os.Symlink("dotfiles/zshrc", dangling); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(dangling, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "dotfiles", "zshrc")); string(data) != "x" {
+		t.Fatalf("dangling target has %q", data)
+	}
+}
+
+func TestInitScriptQuotesPaths(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	home := filepath.Join(t.TempDir(), "it's $HOME `x` \\ é")
+	if err := os.MkdirAll(home, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, aliasFileName(shellBash)), []byte("loaded=yes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script, err := initScript(shellBash, home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(bash, "--norc", "-c", script+`printf %s "$loaded"`).CombinedOutput()
+	if err != nil || string(out) != "yes" {
+		t.Fatalf("script failed: %v %q\n%s", err, out, script)
+	}
+}
+
+func TestRemoveManagedBlock(t *testing.T) {
+	block := managedBlock("if [ -f '/h/.bash_aliases' ]; then\n    . '/h/.bash_aliases'\nfi\n")
+	user := "export PATH=$PATH:~/bin\nfind ~ -name '*.tmp' -delete\nfi_helper\n"
+	got, err := removeManagedBlock(user + "\n" + block + user)
+	if err != nil || got != user+user {
+		t.Fatalf("complete block: %q %v", got, err)
+	}
+
+	// a stray end marker is dropped on its own
+	got, err = removeManagedBlock(user + managedBlockEnd + "\n" + user)
+	if err != nil || got != user+user {
+		t.Fatalf("stray end marker: %q %v", got, err)
+	}
+
+	// the user's code after an unterminated block is never guessed away
+	damaged := user + managedBlockStart + "\nif [ -f x ]; then\n    . x\nfi\n" + user
+	if _, err := removeManagedBlock(damaged); err == nil {
+		t.Fatal("unterminated block removed")
+	}
+	var ube *unterminatedBlockError
+	_, err = removeManagedBlock(damaged + block)
+	if !errors.As(err, &ube) || !strings.HasPrefix(ube.Block, managedBlockStart) {
+		t.Fatalf("start marker inside a later block: %v", err)
+	}
+}
+
+func TestDefaultAliasFileFishHonorsXDG(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("BAM_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", config)
+	if got, want := defaultAliasFile(shellFish, "/h"), filepath.Join(config, "fish", "functions"); got != want {
+		t.Errorf("defaultAliasFile(fish) = %q, want %q", got, want)
+	}
+	if got := defaultAliasFile(shellBash, "/h"); got != "/h/.bash_aliases" {
+		t.Errorf("defaultAliasFile(bash) = %q", got)
+	}
+}
+
+func TestRewriteAliasFileKeepsDuplicates(t *testing.T) {
+	content := "alias ll='ls -l'\n# comment\nalias ll='ls -la'\n"
+	aliases := []Alias{
+		{Name: "ll", Command: "ls -l"},
+		{Name: "ll", Command: "ls -lah"},
+	}
+	want := "alias ll='ls -l'\n# comment\nalias ll='ls -lah'\n"
+	if got := rewriteAliasFile(content, aliases); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// Besides the main alias file, bash and zsh aliases can come from extra
+// files listed in Config.AliasFiles and from *.sh fragments in a drop-in
+// directory (~/.bash_aliases.d). A generated loader file sources all of them
+// so the managed rc block does not change when a file is added.
+
+const (
+	loaderFile    = ".bash_alias_manager_loader.sh"
+	loaderFileZsh = ".bash_alias_manager_loader.zsh"
+)
+
+// aliasDirName returns the drop-in directory of a shell, relative to the
+// home directory, or "" for fish
+func aliasDirName(shell string) string {
+	if shell == shellFish {
+		return ""
+	}
+	return aliasFileName(shell) + ".d"
+}
+
+// loaderName returns the loader file of a shell, relative to the home directory
+func loaderName(shell string) string {
+	if shell == shellZsh {
+		return loaderFileZsh
+	}
+	return loaderFile
+}
+
+// expandPath resolves a configured path: "~/" is the home directory and
+// relative paths are relative to it
+func expandPath(path, home string) string {
+	if path == "~" {
+		return home
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Join(home, path)
+	}
+	return filepath.Clean(path)
+}
+
+// displayPath shortens path for display by replacing the home directory with ~
+func displayPath(path, home string) string {
+	if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "~/" + rel
+	}
+	return path
+}
+
+// aliasSources returns every alias file of the shell in load order: the main
+// file, the extra files in configured order, then the drop-in fragments
+// sorted by name. Later files win when an alias is defined twice.
+func aliasSources(shell, home, main string, extra []string) []string {
+	if shell == shellFish {
+		return nil
+	}
+	sources := []string{main}
+	seen := map[string]bool{sources[0]: true}
+	for _, f := range extra {
+		f = expandPath(f, home)
+		if !seen[f] {
+			seen[f] = true
+			sources = append(sources, f)
+		}
+	}
+	// byte order, so the result does not depend on the locale like a shell glob
+	fragments, _ := filepath.Glob(filepath.Join(home, aliasDirName(shell), "*.sh"))
+	sort.Strings(fragments)
+	for _, f := range fragments {
+		if !seen[f] {
+			seen[f] = true
+			sources = append(sources, f)
+		}
+	}
+	return sources
+}
+
+// renderLoader returns the loader script sourcing the given alias files
+func renderLoader(sources []string) string {
+	var b strings.Builder
+	b.WriteString("# Generated by Bash Alias Manager. Do not edit; it is rewritten when aliases are saved.\n")
+	for _, f := range sources {
+		fmt.Fprintf(&b, "if [ -f %s ]; then\n    . %s\nfi\n", shellQuote(f), shellQuote(f))
+	}
+	return b.String()
+}
+
+// writeLoader regenerates the loader file from the configured alias files,
+// removing it when only the default alias file is used. The managed block
+// loads the default alias file itself, so the loader sources it only when
+// another main file is configured.
+func writeLoader(shell, home string, config Config) error {
+	if shell == shellFish {
+		return nil
+	}
+	path := home + "/" + loaderName(shell)
+	sources := aliasSources(shell, home, aliasFilePath(shell, home, config), config.AliasFiles)
+	if sources[0] == defaultAliasFile(shell, home) {
+		sources = sources[1:]
+	}
+	if len(sources) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	content := renderLoader(sources)
+	if old, err := os.ReadFile(path); err == nil && string(old) == content {
+		return nil
+	}
+	return writeFileAtomic(path, []byte(content), 0644)
+}
+
+// readAliasFile parses the aliases in path, tagging each with its source
+func readAliasFile(path string) ([]Alias, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var aliases []Alias
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if alias, ok := parseAliasLine(scanner.Text()); ok {
+			alias.Source = path
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases, scanner.Err()
+}
+
+// rewriteAliasFile replaces the alias lines of content with aliases. Other
+// lines such as comments and functions are kept; aliases that already had a
+// line stay in place, removed ones are dropped and new ones are appended.
+// An alias defined more than once keeps each definition, matched to the
+// existing lines of that name in order.
+func rewriteAliasFile(content string, aliases []Alias) string {
+	pending := map[string][]Alias{}
+	for _, a := range aliases {
+		pending[a.Name] = append(pending[a.Name], a)
+	}
+	var out []string
+	for _, line := range splitLines(content) {
+		old, ok := parseAliasLine(line)
+		if !ok {
+			out = append(out, line)
+			continue
+		}
+		if defs := pending[old.Name]; len(defs) > 0 {
+			out = append(out, formatAlias(defs[0]))
+			pending[old.Name] = defs[1:]
+		}
+	}
+	for _, a := range aliases {
+		if defs := pending[a.Name]; len(defs) > 0 {
+			out = append(out, formatAlias(defs[0]))
+			pending[a.Name] = defs[1:]
+		}
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// saveAliasSources writes the aliases back to the files they belong to. An
+// alias without a source goes to the main file. Files whose content would
+// not change are left untouched.
+func saveAliasSources(sources []string, aliases []Alias) error {
+	bySource := map[string][]Alias{}
+	for _, a := range aliases {
+		src := a.Source
+		if src == "" {
+			src = sources[0]
+		}
+		bySource[src] = append(bySource[src], a)
+	}
+	files := append([]string{}, sources...)
+	for src := range bySource {
+		known := false
+		for _, f := range sources {
+			known = known || f == src
+		}
+		if !known {
+			files = append(files, src)
+		}
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if os.IsNotExist(err) && len(bySource[path]) == 0 && path != sources[0] {
+			continue
+		}
+		content := rewriteAliasFile(string(data), bySource[path])
+		if content == string(data) && err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createFragment creates an empty drop-in fragment and returns its path
+func createFragment(shell, home, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, "/\\") || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("Invalid file name %q", name)
+	}
+	if !strings.HasSuffix(name, ".sh") {
+		name += ".sh"
+	}
+	dir := filepath.Join(home, aliasDirName(shell))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return "", fmt.Errorf("%s already exists", displayPath(path, home))
+		}
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// sourceSelect returns a selector of the alias files for the add and edit
+// dialogs and a function returning the chosen path. The selector is nil when
+// there is only one file to choose from.
+func (am *AliasManager) sourceSelect(current string) (*widget.Select, func() string) {
+	home, _ := homeDir()
+	sources := aliasSources(am.config.targetShell(), home, am.aliasFile(home), am.config.AliasFiles)
+	if len(sources) < 2 {
+		return nil, func() string { return current }
+	}
+	byLabel := map[string]string{}
+	var labels []string
+	for _, f := range sources {
+		label := displayPath(f, home)
+		byLabel[label] = f
+		labels = append(labels, label)
+	}
+	sel := widget.NewSelect(labels, nil)
+	if current == "" {
+		current = sources[0]
+	}
+	sel.SetSelected(displayPath(current, home))
+	return sel, func() string {
+		if f, ok := byLabel[sel.Selected]; ok {
+			return f
+		}
+		return current
+	}
+}
+
+// sourceLabel returns the origin shown next to an alias in the list, or ""
+// for the main alias file
+func (am *AliasManager) sourceLabel(alias Alias) string {
+	home, _ := homeDir()
+	if alias.Source == "" || alias.Source == am.aliasFile(home) {
+		return ""
+	}
+	return displayPath(alias.Source, home)
+}
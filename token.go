@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Services whose access tokens are stored
+const (
+	serviceGitHub = "github"
+	serviceGitLab = "gitlab"
+	serviceGitea  = "gitea"
+	serviceWebDAV = "webdav"
+	serviceS3     = "s3"
+)
+
+// serviceLabel returns the display name of a token service
+func serviceLabel(service string) string {
+	switch service {
+	case serviceGitLab:
+		return "GitLab"
+	case serviceGitea:
+		return "Gitea"
+	case serviceWebDAV:
+		return "WebDAV"
+	case serviceS3:
+		return "S3"
+	}
+	return "GitHub"
+}
+
+// secretNoun names what is stored for service, e.g. "token"
+func secretNoun(service string) string {
+	switch service {
+	case serviceWebDAV:
+		return "password"
+	case serviceS3:
+		return "secret key"
+	}
+	return "token"
+}
+
+// tokenStorage returns where the token of service is kept. The GitHub token
+// predates the other services and keeps its own config field.
+func (am *AliasManager) tokenStorage(service string) string {
+	if service == serviceGitHub {
+		return am.config.TokenStorage
+	}
+	return am.config.TokenStores[service]
+}
+
+func (am *AliasManager) setTokenStorage(service, storage string) {
+	if service == serviceGitHub {
+		am.config.TokenStorage = storage
+		return
+	}
+	if am.config.TokenStores == nil {
+		am.config.TokenStores = map[string]string{}
+	}
+	if storage == "" {
+		delete(am.config.TokenStores, service)
+		return
+	}
+	am.config.TokenStores[service] = storage
+}
+
+// sessionToken returns the token of service once loaded this session
+func (am *AliasManager) sessionToken(service string) string {
+	if service == serviceGitHub {
+		return am.config.GitHubToken
+	}
+	return am.tokens[service]
+}
+
+func (am *AliasManager) setSessionToken(service, token string) {
+	if service == serviceGitHub {
+		am.config.GitHubToken = token
+		return
+	}
+	if am.tokens == nil {
+		am.tokens = map[string]string{}
+	}
+	am.tokens[service] = token
+}
+
+// withToken calls fn once the GitHub token is available
+func (am *AliasManager) withToken(fn func()) {
+	am.withServiceToken(serviceGitHub, func(string) { fn() })
+}
+
+// withServiceToken calls fn with the token of service: loaded from the
+// keyring, decrypted with the passphrase of the token file, or entered by
+// the user and then stored.
+func (am *AliasManager) withServiceToken(service string, fn func(token string)) {
+	if token := am.sessionToken(service); token != "" {
+		fn(token)
+		return
+	}
+	home, err := homeDir()
+	if err != nil {
+		dialog.ShowError(err, am.window)
+		return
+	}
+	label := serviceLabel(service) + " " + secretNoun(service)
+	switch am.tokenStorage(service) {
+	case tokenStorageKeyring:
+		var token string
+		am.runInBackground("Keyring", "Reading the "+label+" from the keyring…", keyringTimeout, func(context.Context, func(string)) error {
+			var err error
+			token, err = keyringGet(service)
+			return err
+		}, func(err error) {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to read %s from the keyring: %v", label, err), am.window)
+				return
+			}
+			if token == "" {
+				// removed from the keyring outside the app
+				am.askToken(service, fn)
+				return
+			}
+			am.setSessionToken(service, token)
+			fn(token)
+		})
+	case tokenStorageFile:
+		if _, err := os.Stat(tokenFilePath(home, service)); os.IsNotExist(err) {
+			am.askToken(service, fn)
+			return
+		}
+		am.askPassphrase("Unlock "+label, "Enter the passphrase protecting your "+label+".", false, func(passphrase string) {
+			token, err := readTokenFile(home, service, passphrase)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to decrypt %s: %v", label, err), am.window)
+				return
+			}
+			am.setSessionToken(service, token)
+			fn(token)
+		})
+	default:
+		am.askToken(service, fn)
+	}
+}
+
+// askToken asks the user to sign in to service, then stores the token and
+// calls fn
+func (am *AliasManager) askToken(service string, fn func(string)) {
+	if service == serviceGitHub {
+		am.signInGitHub(fn)
+		return
+	}
+	am.promptToken(service, fn)
+}
+
+// promptToken asks for the token of service, stores it and calls fn
+func (am *AliasManager) promptToken(service string, fn func(string)) {
+	label := serviceLabel(service)
+	noun := secretNoun(service)
+	title := strings.ToUpper(noun[:1]) + noun[1:]
+	tokenEntry := widget.NewPasswordEntry()
+	if noun == "token" {
+		tokenEntry.SetPlaceHolder(label + " Personal Access Token")
+	} else {
+		tokenEntry.SetPlaceHolder(label + " " + noun)
+	}
+
+	var d *dialog.CustomDialog
+	form := &widget.Form{
+		Items: []*widget.FormItem{
+			{Text: title + ":", Widget: tokenEntry},
+		},
+		OnSubmit: func() {
+			token := tokenEntry.Text
+			if token == "" {
+				return
+			}
+			if service != serviceGitHub {
+				d.Hide()
+				am.storeToken(service, token, fn)
+				return
+			}
+			config := am.config
+			am.runInBackground("Sign in to GitHub", "Checking the GitHub token…", networkTimeout, func(ctx context.Context, _ func(string)) error {
+				return checkGitHubToken(ctx, token, config)
+			}, func(err error) {
+				if err != nil {
+					dialog.ShowError(err, am.window)
+					return
+				}
+				d.Hide()
+				am.storeToken(service, token, fn)
+			})
+		},
+	}
+	d = dialog.NewCustom("Enter "+label+" "+title, "Cancel", form, am.window)
+	d.Resize(fyne.NewSize(400, 100))
+	d.Show()
+}
+
+// storeToken saves the token in the keyring, or in the encrypted token file
+// when no keyring is available, then calls fn. When the user declines to
+// set a passphrase the token is only kept for this session.
+func (am *AliasManager) storeToken(service, token string, fn func(string)) {
+	label := serviceLabel(service) + " " + secretNoun(service)
+	am.setSessionToken(service, token)
+	am.runInBackground("Keyring", "Saving the "+label+" in the keyring…", keyringTimeout, func(context.Context, func(string)) error {
+		return keyringSet(service, token)
+	}, func(err error) {
+		if err == nil {
+			am.setTokenStorage(service, tokenStorageKeyring)
+			if service == serviceGitHub {
+				am.plainToken = false
+			}
+			if err := am.saveConfig(); err != nil {
+				dialog.ShowError(err, am.window)
+			}
+			fn(token)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Keyring not available: %v\n", err)
+		am.storeTokenFile(service, token, fn)
+	})
+}
+
+// storeTokenFile saves the token encrypted with a passphrase the user
+// chooses, then calls fn
+func (am *AliasManager) storeTokenFile(service, token string, fn func(string)) {
+	label := serviceLabel(service) + " " + secretNoun(service)
+	home, err := homeDir()
+	if err != nil {
+		dialog.ShowError(err, am.window)
+		return
+	}
+	msg := "No keyring is available. Choose a passphrase to encrypt your " + label + "; you will be asked for it once per session."
+	am.askPassphraseOr("Protect "+label, msg, true, func(passphrase string) {
+		if err := writeTokenFile(home, service, token, passphrase); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save %s: %v", label, err), am.window)
+		} else {
+			am.setTokenStorage(service, tokenStorageFile)
+			if service == serviceGitHub {
+				am.plainToken = false
+			}
+			if err := am.saveConfig(); err != nil {
+				dialog.ShowError(err, am.window)
+			}
+		}
+		fn(token)
+	}, func() { fn(token) })
+}
+
+// forgetToken removes the stored token of service, e.g. after its server
+// address or user name changed
+func (am *AliasManager) forgetToken(service string) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	switch am.tokenStorage(service) {
+	case tokenStorageKeyring:
+		if err := keyringDelete(service); err != nil {
+			return err
+		}
+	case tokenStorageFile:
+		if err := os.Remove(tokenFilePath(home, service)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	am.setSessionToken(service, "")
+	am.setTokenStorage(service, "")
+	return am.saveConfig()
+}
+
+// migrateToken moves a token found in plain text in the config file to the
+// keyring or the encrypted token file
+func (am *AliasManager) migrateToken() {
+	if !am.plainToken {
+		return
+	}
+	am.storeToken(serviceGitHub, am.config.GitHubToken, func(string) {})
+}
+
+// askPassphrase shows a passphrase dialog and calls fn with the entered
+// passphrase. With confirm set the passphrase has to be typed twice.
+func (am *AliasManager) askPassphrase(title, message string, confirm bool, fn func(string)) {
+	am.askPassphraseOr(title, message, confirm, fn, func() {})
+}
+
+// askPassphraseOr is askPassphrase calling cancel when the dialog is dismissed
+func (am *AliasManager) askPassphraseOr(title, message string, confirm bool, fn func(string), cancel func()) {
+	passEntry := widget.NewPasswordEntry()
+	confirmEntry := widget.NewPasswordEntry()
+	items := []*widget.FormItem{
+		widget.NewFormItem("", widget.NewLabel(message)),
+		widget.NewFormItem("Passphrase", passEntry),
+	}
+	if confirm {
+		items = append(items, widget.NewFormItem("Repeat", confirmEntry))
+	}
+	dialog.ShowForm(title, "OK", "Cancel", items, func(ok bool) {
+		if !ok {
+			cancel()
+			return
+		}
+		if passEntry.Text == "" {
+			dialog.ShowError(fmt.Errorf("The passphrase must not be empty"), am.window)
+			cancel()
+			return
+		}
+		if confirm && passEntry.Text != confirmEntry.Text {
+			dialog.ShowError(fmt.Errorf("The passphrases do not match"), am.window)
+			cancel()
+			return
+		}
+		fn(passEntry.Text)
+	}, am.window)
+}
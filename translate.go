@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Export targets for translateAliases
+const (
+	exportFish       = "fish"
+	exportZsh        = "zsh"
+	exportNushell    = "nushell"
+	exportPowerShell = "powershell"
+)
+
+// exportTargets lists the export targets in display order
+var exportTargets = []string{exportFish, exportZsh, exportNushell, exportPowerShell}
+
+// powerShellBuiltinAliases are common built-in PowerShell aliases. They take
+// precedence over functions, so they are removed before a function of the
+// same name is defined.
+var powerShellBuiltinAliases = map[string]bool{
+	"cat": true, "cd": true, "cp": true, "curl": true, "diff": true, "dir": true,
+	"echo": true, "gc": true, "gcm": true, "gi": true, "gl": true, "gm": true,
+	"gp": true, "gps": true, "h": true, "kill": true, "ls": true, "man": true,
+	"md": true, "mv": true, "ps": true, "r": true, "rd": true, "rm": true,
+	"sc": true, "sl": true, "sort": true, "sp": true, "type": true, "wget": true,
+}
+
+// TranslationIssue records an alias that could not be translated
+type TranslationIssue struct {
+	Alias  string
+	Reason string
+}
+
+// untranslatable maps a bash construct to the targets that have no direct
+// equivalent for it, with a reason shown to the user
+var untranslatable = []struct {
+	construct string
+	targets   []string
+	reason    string
+}{
+	{"`", []string{exportFish, exportNushell, exportPowerShell}, "backtick command substitution"},
+	{"$((", []string{exportFish, exportNushell, exportPowerShell}, "arithmetic expansion"},
+	{"${", []string{exportFish, exportNushell, exportPowerShell}, "parameter expansion"},
+	{"$(", []string{exportNushell, exportPowerShell}, "command substitution"},
+	{"[[", []string{exportFish, exportNushell, exportPowerShell}, "[[ ]] test"},
+	{"<(", []string{exportFish, exportNushell, exportPowerShell}, "process substitution"},
+	{">(", []string{exportFish, exportNushell, exportPowerShell}, "process substitution"},
+	{"!!", []string{exportFish, exportNushell, exportPowerShell}, "history expansion"},
+	{"&&", []string{exportNushell}, "&& list"},
+	{"||", []string{exportNushell}, "|| list"},
+	{";", []string{exportNushell}, "command list"},
+	{">", []string{exportNushell}, "redirection"},
+	{"<", []string{exportNushell, exportPowerShell}, "input redirection"},
+	{"/dev/null", []string{exportPowerShell}, "/dev/null redirection"},
+	{"$", []string{exportNushell, exportPowerShell}, "shell variable"},
+}
+
+// translationIssue returns why cmd cannot be translated for target, or ""
+func translationIssue(alias Alias, target string) string {
+	switch alias.Type {
+	case "global", "suffix":
+		if target != exportZsh {
+			return "zsh " + alias.Type + " alias"
+		}
+	}
+	if target == exportZsh {
+		// zsh accepts bash alias syntax unchanged
+		return ""
+	}
+	for _, u := range untranslatable {
+		if !strings.Contains(alias.Command, u.construct) {
+			continue
+		}
+		for _, t := range u.targets {
+			if t == target {
+				return u.reason
+			}
+		}
+	}
+	// VAR=value prefixes set the environment for one command in bash only
+	if first, _, _ := strings.Cut(alias.Command, " "); strings.Contains(first, "=") && target != exportFish {
+		return "environment assignment prefix"
+	}
+	return ""
+}
+
+// translateAliases renders aliases in the syntax of the target shell.
+// Aliases that cannot be translated automatically are left out of the code,
+// listed as comments and returned as issues.
+func translateAliases(aliases []Alias, target string) (string, []TranslationIssue, error) {
+	var b strings.Builder
+	var issues []TranslationIssue
+	switch target {
+	case exportFish:
+		b.WriteString("# Aliases exported by Bash Alias Manager; add to ~/.config/fish/config.fish\n")
+	case exportZsh:
+		b.WriteString("# Aliases exported by Bash Alias Manager; source from ~/.zshrc\n")
+	case exportNushell:
+		b.WriteString("# Aliases exported by Bash Alias Manager; add to your config.nu\n")
+	case exportPowerShell:
+		b.WriteString("# Aliases exported by Bash Alias Manager; add to your $PROFILE\n")
+	default:
+		return "", nil, fmt.Errorf("unsupported export target %q", target)
+	}
+
+	for _, alias := range aliases {
+		if reason := translationIssue(alias, target); reason != "" {
+			issues = append(issues, TranslationIssue{Alias: alias.Name, Reason: reason})
+			fmt.Fprintf(&b, "# SKIPPED %s: %s cannot be translated automatically: %s\n", alias.Name, reason, alias.Command)
+			continue
+		}
+		switch target {
+		case exportFish:
+			if alias.Type == "abbr" {
+				fmt.Fprintf(&b, "abbr -a -- %s %s\n", alias.Name, fishQuote(alias.Command))
+			} else {
+				fmt.Fprintf(&b, "alias %s %s\n", alias.Name, fishQuote(alias.Command))
+			}
+		case exportZsh:
+			if alias.Type == "abbr" {
+				alias.Type = ""
+			}
+			b.WriteString(formatAlias(alias) + "\n")
+		case exportNushell:
+			fmt.Fprintf(&b, "alias %s = %s\n", alias.Name, alias.Command)
+		case exportPowerShell:
+			if powerShellBuiltinAliases[alias.Name] {
+				fmt.Fprintf(&b, "Remove-Item Alias:%s -Force -ErrorAction SilentlyContinue\n", alias.Name)
+			}
+			if !strings.ContainsAny(alias.Command, " \t|;&") {
+				fmt.Fprintf(&b, "Set-Alias -Name %s -Value %s\n", alias.Name, alias.Command)
+			} else {
+				// Set-Alias cannot take arguments, so a function forwards them
+				fmt.Fprintf(&b, "function %s { %s @args }\n", alias.Name, alias.Command)
+			}
+		}
+	}
+	return b.String(), issues, nil
+}
+
+// exportFileName suggests a file name for an export target
+func exportFileName(target string) string {
+	switch target {
+	case exportFish:
+		return "aliases.fish"
+	case exportZsh:
+		return "aliases.zsh"
+	case exportNushell:
+		return "aliases.nu"
+	default:
+		return "aliases.ps1"
+	}
+}
+
+// showExport translates the current aliases into another shell's syntax,
+// previews the result and lets the user save it
+func (am *AliasManager) showExport() {
+	preview := widget.NewTextGrid()
+	issuesLabel := widget.NewLabel("")
+	issuesLabel.Wrapping = fyne.TextWrapWord
+	var output string
+
+	targetSelect := widget.NewSelect(exportTargets, func(target string) {
+		out, issues, err := translateAliases(am.aliases, target)
+		if err != nil {
+			dialog.ShowError(err, am.window)
+			return
+		}
+		output = out
+		preview.SetText(out)
+		if len(issues) == 0 {
+			issuesLabel.SetText("All aliases were translated.")
+			return
+		}
+		var lines []string
+		for _, is := range issues {
+			lines = append(lines, fmt.Sprintf("%s: %s", is.Alias, is.Reason))
+		}
+		issuesLabel.SetText(fmt.Sprintf("%d aliases need manual translation:\n%s", len(issues), strings.Join(lines, "\n")))
+	})
+
+	saveBtn := widget.NewButton("Save...", func() {
+		if targetSelect.Selected == "" {
+			return
+		}
+		fd := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			defer writer.Close()
+			if _, err := writer.Write([]byte(output)); err != nil {
+				dialog.ShowError(err, am.window)
+			}
+		}, am.window)
+		fd.SetFileName(exportFileName(targetSelect.Selected))
+		fd.SetFilter(storage.NewExtensionFileFilter([]string{".fish", ".zsh", ".nu", ".ps1", ".sh", ".txt"}))
+		fd.Show()
+	})
+	targetSelect.SetSelected(exportFish)
+
+	top := container.NewBorder(nil, nil, widget.NewLabel("Target shell:"), saveBtn, targetSelect)
+	content := container.NewBorder(top, container.NewVScroll(issuesLabel), nil, nil, container.NewScroll(preview))
+	d := dialog.NewCustom("Export", "Close", content, am.window)
+	d.Resize(fyne.NewSize(700, 500))
+	d.Show()
+}
+
+// runExportCLI implements "export <target>", printing the translation to
+// stdout and the skipped aliases to stderr
+func runExportCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintf(stderr, "Usage: bash-alias-manager export %s\n", strings.Join(exportTargets, "|"))
+		return 2
+	}
+	am := &AliasManager{}
+	if err := am.loadConfig(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := am.loadAliases(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	out, issues, err := translateAliases(am.aliases, args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	fmt.Fprint(stdout, out)
+	for _, is := range issues {
+		fmt.Fprintf(stderr, "skipped %s: %s\n", is.Alias, is.Reason)
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTranslationIssue(t *testing.T) {
+	for _, tt := range []struct {
+		alias Alias
+		want  map[string]string
+	}{
+		{
+			Alias{Name: "ll", Command: "ls -l"},
+			map[string]string{},
+		},
+		{
+			Alias{Name: "src", Command: "cd $HOME/src"},
+			map[string]string{exportNushell: "shell variable", exportPowerShell: "shell variable"},
+		},
+		{
+			Alias{Name: "now", Command: "echo $(date)"},
+			map[string]string{exportNushell: "command substitution", exportPowerShell: "command substitution"},
+		},
+		{
+			Alias{Name: "now", Command: "echo `date`"},
+			map[string]string{exportFish: "backtick command substitution", exportNushell: "backtick command substitution", exportPowerShell: "backtick command substitution"},
+		},
+		{
+			Alias{Name: "up", Command: "cd .. && ls"},
+			map[string]string{exportNushell: "&& list"},
+		},
+		{
+			Alias{Name: "quiet", Command: "make 2>/dev/null"},
+			map[string]string{exportNushell: "redirection", exportPowerShell: "/dev/null redirection"},
+		},
+		{
+			Alias{Name: "d", Command: "diff <(ls a) <(ls b)"},
+			map[string]string{exportFish: "process substitution", exportNushell: "process substitution", exportPowerShell: "process substitution"},
+		},
+		{
+			Alias{Name: "lc", Command: "LC_ALL=C sort"},
+			map[string]string{exportNushell: "environment assignment prefix", exportPowerShell: "environment assignment prefix"},
+		},
+		{
+			Alias{Name: "G", Command: "| grep", Type: "global"},
+			map[string]string{exportFish: "zsh global alias", exportNushell: "zsh global alias", exportPowerShell: "zsh global alias"},
+		},
+		{
+			Alias{Name: "pdf", Command: "evince", Type: "suffix"},
+			map[string]string{exportFish: "zsh suffix alias", exportNushell: "zsh suffix alias", exportPowerShell: "zsh suffix alias"},
+		},
+	} {
+		for _, target := range exportTargets {
+			if got := translationIssue(tt.alias, target); got != tt.want[target] {
+				t.Errorf("%s for %s: %q, want %q", tt.alias.Command, target, got, tt.want[target])
+			}
+		}
+	}
+}
+
+func TestTranslateAliases(t *testing.T) {
+	aliases := []Alias{
+		{Name: "ll", Command: "ls -l"},
+		{Name: "say", Command: "echo 'it''s'"},
+		{Name: "ls", Command: "eza"},
+		{Name: "e", Command: "nvim", Type: "abbr"},
+		{Name: "src", Command: "cd $HOME/src"},
+	}
+	for _, tt := range []struct {
+		target  string
+		lines   []string
+		skipped []string
+	}{
+		{exportFish, []string{
+			"alias ll 'ls -l'",
+			`alias say 'echo \'it\'\'s\''`,
+			"alias ls 'eza'",
+			"abbr -a -- e 'nvim'",
+			"alias src 'cd $HOME/src'",
+		}, nil},
+		{exportZsh, []string{
+			"alias ll='ls -l'",
+			"alias ls='eza'",
+			"alias e='nvim'",
+		}, nil},
+		{exportNushell, []string{
+			"alias ll = ls -l",
+			"alias ls = eza",
+			"# SKIPPED src: shell variable cannot be translated automatically: cd $HOME/src",
+		}, []string{"src"}},
+		{exportPowerShell, []string{
+			"function ll { ls -l @args }",
+			"Remove-Item Alias:ls -Force -ErrorAction SilentlyContinue",
+			"Set-Alias -Name ls -Value eza",
+			"Set-Alias -Name e -Value nvim",
+		}, []string{"src"}},
+	} {
+		out, issues, err := translateAliases(aliases, tt.target)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.target, err)
+		}
+		for _, line := range tt.lines {
+			if !strings.Contains(out, line+"\n") {
+				t.Errorf("%s: missing %q in\n%s", tt.target, line, out)
+			}
+		}
+		var skipped []string
+		for _, is := range issues {
+			skipped = append(skipped, is.Alias)
+		}
+		if !reflect.DeepEqual(skipped, tt.skipped) {
+			t.Errorf("%s: skipped %q, want %q", tt.target, skipped, tt.skipped)
+		}
+	}
+	if _, _, err := translateAliases(aliases, "tcsh"); err == nil {
+		t.Error("expected an error for an unknown target")
+	}
+}
+
+func TestRunExportCLI(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	content := "alias ll='ls -l'\nalias src='cd $HOME/src'\n"
+	if err := os.WriteFile(filepath.Join(home, ".bash_aliases"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runExportCLI([]string{exportNushell}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "alias ll = ls -l\n") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	// only the skipped aliases, so the list can be read by scripts
+	if got := stderr.String(); got != "skipped src: shell variable\n" {
+		t.Errorf("stderr = %q", got)
+	}
+}
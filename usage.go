@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// AliasUsage records how often an alias was typed and when it was last seen.
+// LastUsed is zero when the history has no timestamps.
+type AliasUsage struct {
+	Count    int
+	LastUsed time.Time
+}
+
+const (
+	usageHookFile    = ".bash_alias_manager_hook.sh"
+	usageHookFileZsh = ".bash_alias_manager_hook.zsh"
+	// legacyUsageLogFile is where versions before XDG support kept the log
+	legacyUsageLogFile = ".bash_alias_manager_usage.log"
+)
+
+// usageHookScript is installed when usage logging is enabled. It appends the
+// last history entry with a timestamp to the usage log after every prompt.
+// An empty command line leaves the last entry in place, so an entry is only
+// logged once by its history number; the same command typed again gets a new
+// number and is logged again.
+const usageHookScript = `# Installed by Bash Alias Manager: logs commands so alias usage can be counted
+__bam_log_usage() {
+    local entry num cmd
+    entry=$(HISTTIMEFORMAT= history 1)
+    read -r num _ <<< "$entry"
+    cmd=$(printf '%%s\n' "$entry" | sed -E 's/^ *[0-9]+\*? *//')
+    if [ -n "$cmd" ] && [ "$num" != "$__bam_last_num" ]; then
+        [ -e %[1]s ] || (umask 077 && : >> %[1]s)
+        printf '%%s\t%%s\n' "$(date +%%s)" "$cmd" >> %[1]s
+    fi
+    __bam_last_num=$num
+}
+case ";$PROMPT_COMMAND;" in
+    *";__bam_log_usage;"*) ;;
+    *) PROMPT_COMMAND="__bam_log_usage${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`
+
+// usageHookScriptZsh is the zsh equivalent of usageHookScript, run from a
+// precmd hook
+const usageHookScriptZsh = `# Installed by Bash Alias Manager: logs commands so alias usage can be counted
+__bam_log_usage() {
+    local num cmd
+    read -r num _ <<< "$(fc -l -1)"
+    cmd=$(fc -ln -1)
+    if [ -n "$cmd" ] && [ "$num" != "$__bam_last_num" ]; then
+        [ -e %[1]s ] || (umask 077 && : >> %[1]s)
+        printf '%%s\t%%s\n' "$(date +%%s)" "$cmd" >> %[1]s
+    fi
+    __bam_last_num=$num
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook precmd __bam_log_usage
+`
+
+// usageHookName returns the usage hook file of a shell, relative to the home directory
+func usageHookName(shell string) string {
+	if shell == shellZsh {
+		return usageHookFileZsh
+	}
+	return usageHookFile
+}
+
+// commandHeads returns the first word of every pipeline or list segment of a
+// command line, which is where an alias can be expanded.
+func commandHeads(line string) []string {
+	r := strings.NewReplacer("&&", ";", "||", ";", "|", ";", "&", ";")
+	var heads []string
+	for _, seg := range strings.Split(r.Replace(line), ";") {
+		words := strings.Fields(seg)
+		if len(words) == 0 {
+			continue
+		}
+		head := words[0]
+		// aliases are expanded after sudo when sudo itself is aliased with a trailing space
+		if head == "sudo" && len(words) > 1 {
+			heads = append(heads, head)
+			head = words[1]
+		}
+		heads = append(heads, head)
+	}
+	return heads
+}
+
+// computeAliasUsage counts how often each alias appears in the given entries
+func computeAliasUsage(entries []HistoryEntry, aliases []Alias) map[string]AliasUsage {
+	usage := map[string]AliasUsage{}
+	names := map[string]bool{}
+	for _, a := range aliases {
+		names[a.Name] = true
+		usage[a.Name] = AliasUsage{}
+	}
+	for _, e := range entries {
+		for _, head := range commandHeads(e.Command) {
+			if !names[head] {
+				continue
+			}
+			u := usage[head]
+			u.Count++
+			if e.Time.After(u.LastUsed) {
+				u.LastUsed = e.Time
+			}
+			usage[head] = u
+		}
+	}
+	return usage
+}
+
+// parseUsageLog reads "<unix seconds>\t<command>" lines written by the hook
+func parseUsageLog(r io.Reader) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		secs, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, HistoryEntry{Command: parts[1], Time: time.Unix(secs, 0)})
+	}
+	return entries, scanner.Err()
+}
+
+// usageEntries returns the commands used for statistics. The usage log is
+// preferred when logging is enabled because it is complete and timestamped;
+// otherwise the shell history is used.
+func (am *AliasManager) usageEntries() ([]HistoryEntry, error) {
+	if am.config.UsageLogging {
+		home, err := homeDir()
+		if err != nil {
+			return nil, err
+		}
+		file, err := os.Open(usageLogPath(home))
+		if err == nil {
+			defer file.Close()
+			entries, err := parseUsageLog(file)
+			if err != nil || len(entries) > 0 {
+				return entries, err
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return readShellHistory(am.config.targetShell())
+}
+
+// reloadUsage recomputes usage statistics for the current aliases
+func (am *AliasManager) reloadUsage() error {
+	entries, err := am.usageEntries()
+	if err != nil {
+		am.usage = map[string]AliasUsage{}
+		return err
+	}
+	am.usage = computeAliasUsage(entries, am.aliases)
+	if home, err := homeDir(); err == nil {
+		addRestoredUsage(am.usage, loadRestoredUsage(home))
+	}
+	return nil
+}
+
+// usageText formats usage for the list column
+func (am *AliasManager) usageText(name string) string {
+	u, ok := am.usage[name]
+	if !ok {
+		return ""
+	}
+	if u.Count == 0 {
+		return "unused"
+	}
+	if u.LastUsed.IsZero() {
+		return fmt.Sprintf("%d uses", u.Count)
+	}
+	return fmt.Sprintf("%d uses, last %s", u.Count, u.LastUsed.Format("2006-01-02"))
+}
+
+// setUsageLogging installs or removes the PROMPT_COMMAND logging hook
+func (am *AliasManager) setUsageLogging(enabled bool) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+	shell := am.config.targetShell()
+	if shell == shellFish && enabled {
+		return fmt.Errorf("Usage logging is not supported for fish; usage is read from the fish history instead")
+	}
+	hookPath := home + "/" + usageHookName(shell)
+	if !enabled {
+		if err := os.Remove(hookPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		if err := writeUsageHook(shell, home); err != nil {
+			return err
+		}
+		if err := am.ensureShellIntegration(); err != nil {
+			return err
+		}
+	}
+	am.config.UsageLogging = enabled
+	return am.saveConfig()
+}
+
+// writeUsageHook writes the logging hook of the shell, pointing it at the usage
+// log. The log holds every command typed, so it is only readable by the user.
+func writeUsageHook(shell, home string) error {
+	logPath := usageLogPath(home)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0700); err != nil {
+		return err
+	}
+	log, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	log.Close()
+	if err := os.Chmod(logPath, 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(home, usageHookName(shell)), []byte(usageHook(shell, home)), 0644)
+}
+
+// usageHook returns the logging hook of the shell
+func usageHook(shell, home string) string {
+	if shell == shellZsh {
+		return fmt.Sprintf(usageHookScriptZsh, shellQuote(usageLogPath(home)))
+	}
+	return fmt.Sprintf(usageHookScript, shellQuote(usageLogPath(home)))
+}
+
+// unusedAliases returns the indexes of aliases not used within the given
+// number of days. Aliases that were used but have no timestamp are kept.
+func (am *AliasManager) unusedAliases(days int) []int {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var out []int
+	for i, a := range am.aliases {
+		u := am.usage[a.Name]
+		if u.Count == 0 || (!u.LastUsed.IsZero() && u.LastUsed.Before(cutoff)) {
+			out = append(out, i)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return am.usage[am.aliases[out[i]].Name].LastUsed.Before(am.usage[am.aliases[out[j]].Name].LastUsed)
+	})
+	return out
+}
+
+// showUnusedReport lists aliases unused for N days and allows removing them in bulk
+func (am *AliasManager) showUnusedReport() {
+	if err := am.reloadUsage(); err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to read usage data: %v", err), am.window)
+		return
+	}
+
+	daysEntry := widget.NewEntry()
+	daysEntry.SetText("30")
+	selected := map[string]bool{}
+	var unused []int
+
+	list := widget.NewList(
+		func() int {
+			return len(unused)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewCheck("", nil), widget.NewLabel("usage"), widget.NewLabel("template"))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			a := am.aliases[unused[i]]
+			row := o.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s = %s", a.Name, a.Command))
+			check := row.Objects[1].(*widget.Check)
+			check.OnChanged = nil
+			check.SetChecked(selected[a.Name])
+			check.OnChanged = func(v bool) {
+				selected[a.Name] = v
+			}
+			row.Objects[2].(*widget.Label).SetText(am.usageText(a.Name))
+		},
+	)
+
+	update := func() {
+		days, err := strconv.Atoi(strings.TrimSpace(daysEntry.Text))
+		if err != nil || days < 0 {
+			return
+		}
+		unused = am.unusedAliases(days)
+		selected = map[string]bool{}
+		list.Refresh()
+	}
+	daysEntry.OnChanged = func(string) { update() }
+	update()
+
+	logCheck := widget.NewCheck("Log alias usage from new shells (PROMPT_COMMAND hook)", func(v bool) {
+		if err := am.setUsageLogging(v); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+	})
+	logCheck.Checked = am.config.UsageLogging
+
+	removeBtn := widget.NewButton("Remove selected", func() {
+		var n int
+		for _, v := range selected {
+			if v {
+				n++
+			}
+		}
+		if n == 0 {
+			return
+		}
+		confirm := dialog.NewConfirm("Remove Aliases", fmt.Sprintf("Remove %d unused aliases?", n), func(ok bool) {
+			if !ok {
+				return
+			}
+			kept := am.aliases[:0]
+			for _, a := range am.aliases {
+				if !selected[a.Name] {
+					kept = append(kept, a)
+				}
+			}
+			am.aliases = kept
+			am.selectedIndex = -1
+			am.list.UnselectAll()
+			am.refreshList()
+			if err := am.saveAliases(); err != nil {
+				dialog.ShowError(err, am.window)
+			}
+			update()
+		}, am.window)
+		confirm.Show()
+	})
+
+	top := container.NewBorder(nil, nil, widget.NewLabel("Unused for days:"), nil, daysEntry)
+	bottom := container.NewVBox(logCheck, container.NewHBox(removeBtn))
+	content := container.NewBorder(top, bottom, nil, nil, list)
+	d := dialog.NewCustom("Unused Aliases", "Close", content, am.window)
+	d.Resize(fyne.NewSize(600, 450))
+	d.Show()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandHeads(t *testing.T) {
+	for line, want := range map[string][]string{
+		"ll":                         {"ll"},
+		"  gs --short ":              {"gs"},
+		"ll | grep x | wc -l":        {"ll", "grep", "wc"},
+		"make && gp || echo failed":  {"make", "gp", "echo"},
+		"cd src; ll &":               {"cd", "ll"},
+		"sleep 1 & gs":               {"sleep", "gs"},
+		"sudo apt update":            {"sudo", "apt"},
+		"sudo":                       {"sudo"},
+		"echo 'a' ;; ;":              {"echo"},
+		"":                           nil,
+		"FOO=1 ll":                   {"FOO=1"},
+		"git status|grep -v gitdiff": {"git", "grep"},
+	} {
+		if got := commandHeads(line); !reflect.DeepEqual(got, want) {
+			t.Errorf("commandHeads(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestParseUsageLog(t *testing.T) {
+	log := "1700000000\tll -a\n" +
+		"garbage\n" +
+		"notanumber\tgs\n" +
+		"1700000060\tgs | grep x\twith a tab\n" +
+		"\n"
+	entries, err := parseUsageLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HistoryEntry{
+		{Command: "ll -a", Time: time.Unix(1700000000, 0)},
+		{Command: "gs | grep x\twith a tab", Time: time.Unix(1700000060, 0)},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v, want %+v", entries, want)
+	}
+}
+
+func TestComputeAliasUsage(t *testing.T) {
+	aliases := []Alias{{Name: "g"}, {Name: "gs"}, {Name: "ll"}, {Name: "never"}}
+	first, last := time.Unix(1700000000, 0), time.Unix(1700086400, 0)
+	entries := []HistoryEntry{
+		{Command: "ll", Time: first},
+		{Command: "ll", Time: last},
+		{Command: "ll"},
+		// a name that merely starts with an alias is not the alias
+		{Command: "git status"},
+		{Command: "gsutil ls"},
+		{Command: "lll"},
+		// every pipeline and list segment can expand an alias
+		{Command: "gs | g log && ll", Time: first},
+		{Command: "sudo ll /root"},
+		// arguments are not expanded
+		{Command: "echo ll gs"},
+	}
+	got := computeAliasUsage(entries, aliases)
+	want := map[string]AliasUsage{
+		"g":     {Count: 1, LastUsed: first},
+		"gs":    {Count: 1, LastUsed: first},
+		"ll":    {Count: 5, LastUsed: last},
+		"never": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestUsageHookLogsRepeatedCommands(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	if err := writeUsageHook(shellBash, home); err != nil {
+		t.Fatal(err)
+	}
+	// a prompt after an empty command line sees the same history entry again
+	script := "set -o history; HISTFILE=/dev/null\n" +
+		". " + shellQuote(home+"/"+usageHookFile) + "\n" +
+		"history -s ll; __bam_log_usage; __bam_log_usage\n" +
+		"history -s ll; __bam_log_usage\n" +
+		"history -s ll; __bam_log_usage; __bam_log_usage\n"
+	if out, err := exec.Command(bash, "--norc", "-c", script).CombinedOutput(); err != nil {
+		t.Fatalf("hook failed: %v\n%s", err, out)
+	}
+	file, err := os.Open(usageLogPath(home))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	entries, err := parseUsageLog(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := computeAliasUsage(entries, []Alias{{Name: "ll"}})["ll"]; u.Count != 3 {
+		t.Errorf("ll counted %d times, want 3 (log %+v)", u.Count, entries)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// webdavStore keeps backup objects as files in a WebDAV collection, which is
+// created on the first backup
+type webdavStore struct {
+	// base is the collection URL, ending in a slash
+	base     string
+	user     string
+	password string
+}
+
+// openWebDAVProvider opens the WebDAV provider once the password is available
+func openWebDAVProvider(am *AliasManager, fn func(BackupProvider)) {
+	if am.config.WebDAVURL == "" {
+		dialog.ShowInformation("Backup", "Enter the WebDAV folder to back up to in Settings → Backup.", am.window)
+		return
+	}
+	am.withServiceToken(serviceWebDAV, func(password string) {
+		store := &webdavStore{
+			base:     strings.TrimRight(forgeURL(am.config.WebDAVURL, ""), "/") + "/",
+			user:     am.config.WebDAVUser,
+			password: password,
+		}
+		fn(&objectProvider{name: "WebDAV folder", store: store})
+	})
+}
+
+// request sends a request for the object name, or the collection itself
+// when name is empty
+func (w *webdavStore) request(ctx context.Context, method, name string, body []byte, header map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, w.base+url.PathEscape(name), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(w.user, w.password)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	data, err := objectRequest(req)
+	if isStatus(err, 401) {
+		return nil, fmt.Errorf("wrong WebDAV user name or password: %v", err)
+	}
+	return data, err
+}
+
+func (w *webdavStore) put(ctx context.Context, name string, data []byte) error {
+	_, err := w.request(ctx, "PUT", name, data, nil)
+	if isStatus(err, 404) || isStatus(err, 409) {
+		// the collection does not exist yet
+		if _, err := w.request(ctx, "MKCOL", "", nil, nil); err != nil {
+			return fmt.Errorf("creating the WebDAV folder failed: %v", err)
+		}
+		_, err = w.request(ctx, "PUT", name, data, nil)
+	}
+	return err
+}
+
+func (w *webdavStore) get(ctx context.Context, name string) ([]byte, error) {
+	data, err := w.request(ctx, "GET", name, nil, nil)
+	if isStatus(err, 404) {
+		return nil, errNoBackup
+	}
+	return data, err
+}
+
+// webdavMultistatus is the part of a PROPFIND response used here
+type webdavMultistatus struct {
+	Responses []struct {
+		Href string `xml:"href"`
+	} `xml:"response"`
+}
+
+func (w *webdavStore) list(ctx context.Context, prefix string) ([]string, error) {
+	body := []byte(`<?xml version="1.0" encoding="utf-8"?><propfind xmlns="DAV:"><prop><resourcetype/></prop></propfind>`)
+	data, err := w.request(ctx, "PROPFIND", "", body, map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml",
+	})
+	if isStatus(err, 404) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ms webdavMultistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("invalid WebDAV response: %v", err)
+	}
+	var names []string
+	for _, r := range ms.Responses {
+		// hrefs are absolute paths or URLs; collections end in a slash
+		href := r.Href
+		if u, err := url.Parse(href); err == nil {
+			href = u.Path
+		}
+		if strings.HasSuffix(href, "/") {
+			continue
+		}
+		if name := path.Base(href); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// webdavSettings configures the WebDAV folder and user. Changing either
+// forgets the stored password.
+func webdavSettings(am *AliasManager) fyne.CanvasObject {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://cloud.example.com/remote.php/dav/files/me/aliases")
+	urlEntry.SetText(am.config.WebDAVURL)
+	userEntry := widget.NewEntry()
+	userEntry.SetText(am.config.WebDAVUser)
+
+	applyBtn := widget.NewButton("Apply", func() {
+		folder := strings.TrimSpace(urlEntry.Text)
+		user := strings.TrimSpace(userEntry.Text)
+		if folder != am.config.WebDAVURL || user != am.config.WebDAVUser {
+			if err := am.forgetToken(serviceWebDAV); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to remove the WebDAV password: %v", err), am.window)
+			}
+		}
+		am.config.WebDAVURL = folder
+		am.config.WebDAVUser = user
+		if err := am.saveConfig(); err != nil {
+			dialog.ShowError(err, am.window)
+		}
+	})
+	form := widget.NewForm(
+		widget.NewFormItem("Folder URL", urlEntry),
+		widget.NewFormItem("User name", userEntry),
+	)
+	return container.NewVBox(form, container.NewHBox(applyBtn))
+}
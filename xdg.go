@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The config file lives in $XDG_CONFIG_HOME/bash-alias-manager, the usage log
+// in $XDG_DATA_HOME/bash-alias-manager and downloads in
+// $XDG_CACHE_HOME/bash-alias-manager. Files the startup files source (alias
+// files, loader and usage hook) stay in the home directory.
+
+const (
+	appDirName = "bash-alias-manager"
+
+	// legacyConfigFile is where versions before XDG support kept the config
+	legacyConfigFile = ".bash_alias_manager.json"
+)
+
+// xdgDir returns the application directory below the XDG base directory in
+// env, or below home/fallback when it is unset. BAM_HOME takes precedence
+// over the XDG variables so a scratch home stays self-contained.
+func xdgDir(env, home, fallback string) string {
+	if dir := os.Getenv(env); dir != "" && os.Getenv("BAM_HOME") == "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDirName)
+	}
+	return filepath.Join(home, fallback, appDirName)
+}
+
+// configDir returns the directory of the config file
+func configDir(home string) string {
+	return xdgDir("XDG_CONFIG_HOME", home, ".config")
+}
+
+// dataDir returns the directory of the usage log
+func dataDir(home string) string {
+	return xdgDir("XDG_DATA_HOME", home, ".local/share")
+}
+
+// cacheDir returns the directory for downloads and other disposable files
+func cacheDir(home string) string {
+	return xdgDir("XDG_CACHE_HOME", home, ".cache")
+}
+
+// configPath returns the location of the config file
+func configPath(home string) string {
+	if configFileOverride != "" {
+		return expandPath(configFileOverride, home)
+	}
+	return filepath.Join(configDir(home), "config.json")
+}
+
+// usageLogPath returns the location of the usage log
+func usageLogPath(home string) string {
+	return filepath.Join(dataDir(home), "usage.log")
+}
+
+// restoredUsagePath returns the usage statistics restored from backups
+func restoredUsagePath(home string) string {
+	return filepath.Join(dataDir(home), "restored_usage.json")
+}
+
+// pendingBackupPath returns where a backup that has not been sent yet is
+// kept until it is
+func pendingBackupPath(home string) string {
+	return filepath.Join(dataDir(home), "pending_backup.json")
+}
+
+// migrateLegacyFiles moves the config file and usage log from the home
+// directory to the XDG directories, unless they have been moved or the new
+// location already exists, and rewrites installed usage hooks that differ
+// from the current one.
+func migrateLegacyFiles(home string) error {
+	if configFileOverride == "" {
+		if err := moveFile(filepath.Join(home, legacyConfigFile), configPath(home), 0600); err != nil {
+			return fmt.Errorf("Failed to migrate config file: %v", err)
+		}
+	}
+	legacyLog := filepath.Join(home, legacyUsageLogFile)
+	if _, err := os.Stat(legacyLog); err == nil {
+		if err := moveFile(legacyLog, usageLogPath(home), 0600); err != nil {
+			return fmt.Errorf("Failed to migrate usage log: %v", err)
+		}
+	}
+	// installed hooks may still append to the old log file or come from an
+	// older version; rewriting them also makes the log private
+	for _, shell := range []string{shellBash, shellZsh} {
+		data, err := os.ReadFile(filepath.Join(home, usageHookName(shell)))
+		if err != nil || string(data) == usageHook(shell, home) {
+			continue
+		}
+		if err := writeUsageHook(shell, home); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveFile moves src to dst unless src is missing or dst exists. The copy is
+// written before src is removed, and works across file systems.
+func moveFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer in.Close()
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(dst, data, perm); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
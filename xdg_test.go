@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes content to path, creating its directory
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkFile fails the test unless path holds want; an empty want means the
+// file must not exist
+func checkFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if want == "" {
+		if !os.IsNotExist(err) {
+			t.Errorf("%s exists: %v", path, err)
+		}
+		return
+	}
+	if err != nil || string(data) != want {
+		t.Errorf("%s = %q, %v; want %q", path, data, err, want)
+	}
+}
+
+func TestMigrateLegacyFiles(t *testing.T) {
+	home := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("BAM_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(xdg, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(xdg, "data"))
+	writeTestFile(t, filepath.Join(home, legacyConfigFile), `{"shell":"zsh"}`)
+	writeTestFile(t, filepath.Join(home, legacyUsageLogFile), "1700000000\tll\n")
+	writeTestFile(t, filepath.Join(home, usageHookFile), "# old hook\n")
+
+	if err := migrateLegacyFiles(home); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, filepath.Join(xdg, "config", appDirName, "config.json"), `{"shell":"zsh"}`)
+	checkFile(t, filepath.Join(xdg, "data", appDirName, "usage.log"), "1700000000\tll\n")
+	checkFile(t, filepath.Join(home, legacyConfigFile), "")
+	checkFile(t, filepath.Join(home, legacyUsageLogFile), "")
+	if fi, err := os.Stat(usageLogPath(home)); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("usage log mode %v, %v", fi.Mode(), err)
+	}
+	// the installed hook now writes to the new log; none is added for zsh
+	checkFile(t, filepath.Join(home, usageHookFile), usageHook(shellBash, home))
+	checkFile(t, filepath.Join(home, usageHookFileZsh), "")
+}
+
+func TestMigrateLegacyFilesKeepsExisting(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	writeTestFile(t, filepath.Join(home, legacyConfigFile), `{"shell":"zsh"}`)
+	writeTestFile(t, configPath(home), `{"shell":"fish"}`)
+
+	if err := migrateLegacyFiles(home); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, configPath(home), `{"shell":"fish"}`)
+	checkFile(t, filepath.Join(home, legacyConfigFile), `{"shell":"zsh"}`)
+}
+
+func TestMigrateLegacyFilesBAMHome(t *testing.T) {
+	home := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(xdg, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(xdg, "data"))
+	writeTestFile(t, filepath.Join(home, legacyConfigFile), "{}")
+	writeTestFile(t, filepath.Join(home, legacyUsageLogFile), "")
+
+	if err := migrateLegacyFiles(home); err != nil {
+		t.Fatal(err)
+	}
+	// the scratch home stays self-contained
+	checkFile(t, filepath.Join(home, ".config", appDirName, "config.json"), "{}")
+	if _, err := os.Stat(filepath.Join(home, ".local", "share", appDirName, "usage.log")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(xdg, "config")); !os.IsNotExist(err) {
+		t.Errorf("XDG_CONFIG_HOME written: %v", err)
+	}
+}
+
+func TestMigrateLegacyFilesLeavesCurrentHook(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("BAM_HOME", home)
+	if err := writeUsageHook(shellZsh, home); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(home, usageHookFileZsh)
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(hook, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateLegacyFiles(home); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(hook); err != nil || !fi.ModTime().Equal(old) {
+		t.Errorf("current hook rewritten: %v", err)
+	}
+}